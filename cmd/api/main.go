@@ -31,7 +31,7 @@ func main() {
 	}
 	defer database.CloseDatabase(db)
 
-	middlewares.FiberAppMiddlewares(app)
+	middlewares.FiberAppMiddlewares(app, cfg.CORS)
 	modules.Setup(app, db, cfg)
 
 	// Start server and handle graceful shutdown
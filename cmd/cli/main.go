@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"varaden/server/config"
+	"varaden/server/internal/database"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, db *sql.DB, args []string) error
+}
+
+var commands = map[string]command{
+	"roles:grant": {
+		usage: "roles:grant <email> <role>\tgrant a role, e.g. to bootstrap the first admin",
+		run:   grantRole,
+	},
+	"roles:list": {
+		usage: "roles:list <email>\tshow the roles of a user",
+		run:   listRoles,
+	},
+	"roles:revoke": {
+		usage: "roles:revoke <email> <role>\trevoke a role",
+		run:   revokeRole,
+	},
+	"sessions:revoke": {
+		usage: "sessions:revoke <email>\tforce-logout a user by revoking all of their sessions",
+		run:   revokeSessions,
+	},
+	"tokens:hash": {
+		usage: "tokens:hash\thash plaintext tokens stored before tokens were hashed",
+		run:   hashTokens,
+	},
+}
+
+// Management commands for support staff. Configuration flags are shared with
+// the API server and must come before the command, e.g.
+//
+//	go run ./cmd/cli -db-host=localhost sessions:revoke user@example.com
+func main() {
+	cfg := config.AppConfig()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	db, err := database.InitDatabase(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = cmd.run(context.Background(), db, args[1:])
+	database.CloseDatabase(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: cli [flags] <command> [args]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	rbacServices "varaden/server/internal/modules/rbac/services"
+	userServices "varaden/server/internal/modules/user/services"
+)
+
+func listRoles(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: roles:list <email>")
+	}
+
+	user, err := userServices.New(db).GetUserByEmail(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("user %q not found: %w", args[0], err)
+	}
+
+	roles, err := rbacServices.New(db).ListUserRoles(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %s\n", user.Email, strings.Join(roles, ", "))
+	return nil
+}
+
+func grantRole(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: roles:grant <email> <role>")
+	}
+
+	user, err := userServices.New(db).GetUserByEmail(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("user %q not found: %w", args[0], err)
+	}
+
+	queries := rbacServices.New(db)
+	role, err := queries.GetRoleByName(ctx, args[1])
+	if err != nil {
+		return fmt.Errorf("role %q not found: %w", args[1], err)
+	}
+
+	granted, err := queries.GrantUserRole(ctx, rbacServices.GrantUserRoleParams{
+		UserID: user.ID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if granted == 0 {
+		fmt.Printf("%s already has the %s role\n", user.Email, role.Name)
+		return nil
+	}
+
+	fmt.Printf("Granted the %s role to %s\n", role.Name, user.Email)
+	return nil
+}
+
+func revokeRole(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: roles:revoke <email> <role>")
+	}
+
+	user, err := userServices.New(db).GetUserByEmail(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("user %q not found: %w", args[0], err)
+	}
+
+	queries := rbacServices.New(db)
+	role, err := queries.GetRoleByName(ctx, args[1])
+	if err != nil {
+		return fmt.Errorf("role %q not found: %w", args[1], err)
+	}
+
+	revoked, err := queries.RevokeUserRole(ctx, rbacServices.RevokeUserRoleParams{
+		UserID: user.ID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return fmt.Errorf("%s does not have the %s role", user.Email, role.Name)
+	}
+
+	fmt.Printf("Revoked the %s role from %s\n", role.Name, user.Email)
+	return nil
+}
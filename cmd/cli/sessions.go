@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+)
+
+func revokeSessions(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: sessions:revoke <email>")
+	}
+
+	user, err := userServices.New(db).GetUserByEmail(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("user %q not found: %w", args[0], err)
+	}
+
+	revoked, err := authServices.New(db).RevokeAllUserSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked %d session(s) of %s\n", revoked, user.Email)
+	return nil
+}
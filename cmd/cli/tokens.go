@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	authServices "varaden/server/internal/modules/auth/services"
+)
+
+// hashTokens replaces the plaintext one-time codes and link tokens stored
+// before tokens were hashed with their keyed hash, so they keep working. Run it
+// once after migrating, with the same -encryption-key as the API server.
+func hashTokens(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: tokens:hash")
+	}
+
+	queries := authServices.New(db)
+	tokens, err := queries.ListUnhashedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if err := queries.SetTokenHash(ctx, authServices.SetTokenHashParams{
+			ID:        token.ID,
+			TokenHash: authServices.HashToken(token.UserID, token.Type, token.Token.String),
+		}); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Hashed %d tokens\n", len(tokens))
+	return nil
+}
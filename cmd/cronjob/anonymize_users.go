@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+	rbacServices "varaden/server/internal/modules/rbac/services"
+	userServices "varaden/server/internal/modules/user/services"
+
+	"github.com/google/uuid"
+)
+
+// anonymizeBatch is how many accounts are looked up at a time.
+const anonymizeBatch = 100
+
+// anonymizeUsers erases the personal data of accounts whose deletion grace
+// period has ended. The users row stays, scrubbed, so that records pointing at
+// it keep working; auth events outlive it until the retention purge.
+func anonymizeUsers(ctx context.Context, db *sql.DB, cfg config.AllConfig) error {
+	users := userServices.New(db)
+
+	anonymized := 0
+	for {
+		ids, err := users.ListUsersDueForAnonymization(ctx, anonymizeBatch)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := anonymizeUser(ctx, db, id); err != nil {
+				return fmt.Errorf("user %s: %w", id, err)
+			}
+			anonymized++
+		}
+		if len(ids) < anonymizeBatch {
+			break
+		}
+	}
+
+	fmt.Printf("Anonymized %d deleted account(s)\n", anonymized)
+	return nil
+}
+
+// anonymizeUser deletes everything the modules hold about the user and
+// scrubs the users row, all or nothing. Auth events stay for the audit trail,
+// but lose the IP address and user agent of the user's own requests.
+func anonymizeUser(ctx context.Context, db *sql.DB, id uuid.UUID) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Auth data goes first, as it finds phone OTP sends by the user's phone
+	if err := authServices.New(tx).DeleteUserAuthData(ctx, id); err != nil {
+		return err
+	}
+	if err := rbacServices.New(tx).DeleteUserRoles(ctx, id); err != nil {
+		return err
+	}
+	if err := userServices.New(tx).AnonymizeUser(ctx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+)
+
+// purgeAuthEvents deletes auth events older than the retention period.
+func purgeAuthEvents(ctx context.Context, db *sql.DB, cfg config.AllConfig) error {
+	if cfg.Audit.RetentionDays == 0 {
+		return nil
+	}
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -cfg.Audit.RetentionDays)
+	deleted, err := authServices.New(db).DeleteAuthEventsBefore(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted %d auth event(s) from before %s\n", deleted, cutoff.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"varaden/server/config"
+	"varaden/server/internal/database"
+)
+
+type job struct {
+	name string
+	run  func(ctx context.Context, db *sql.DB, cfg config.AllConfig) error
+}
+
+var jobs = []job{
+	{name: "purge auth events", run: purgeAuthEvents},
+	{name: "anonymize deleted users", run: anonymizeUsers},
+}
+
+// Maintenance jobs, run once per invocation. Schedule it with cron or a
+// Kubernetes CronJob, e.g. daily, with the same flags as the API server:
+//
+//	go run ./cmd/cronjob -db-host=localhost
+func main() {
+	cfg := config.AppConfig()
+
+	db, err := database.InitDatabase(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// A failed job does not stop the others
+	failed := false
+	for _, job := range jobs {
+		if err := job.run(context.Background(), db, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", job.name, err)
+			failed = true
+		}
+	}
+
+	database.CloseDatabase(db)
+	if failed {
+		os.Exit(1)
+	}
+}
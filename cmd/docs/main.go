@@ -21,6 +21,9 @@ import (
 // @securityDefinitions.apikey	JWT
 // @in							header
 // @name						Authorization
+// @securityDefinitions.apikey	APIKey
+// @in							header
+// @name						X-API-Key
 func main() {
 	app := fiber.New()
 
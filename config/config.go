@@ -3,6 +3,12 @@ package config
 import (
 	"flag"
 	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"varaden/server/internal/utils"
 )
 
 type DBConfig struct {
@@ -27,10 +33,112 @@ type SMTPConfig struct {
 	From     string
 }
 
+type SMSConfig struct {
+	// LogFile receives every text message in development; empty logs only
+	// their recipients.
+	LogFile string
+}
+
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Issuer is the OpenID Connect issuer URL; endpoints and signing keys are
+	// discovered from its /.well-known/openid-configuration document.
+	Issuer string
+}
+
+type RateLimitConfig struct {
+	// Store is where hits are counted: "memory" for a single instance or
+	// "postgres" to share limits between instances and keep them over restarts.
+	Store string
+
+	// Requests allowed per client IP, and per account (email or user ID), on
+	// each rate limited endpoint within the sliding window.
+	IPLimit       int
+	IPWindow      time.Duration
+	AccountLimit  int
+	AccountWindow time.Duration
+}
+
+type LockoutConfig struct {
+	// Threshold failed logins in a row lock the account for Duration. Each
+	// lock after that without a successful login in between doubles the
+	// duration, up to MaxDuration.
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+type AuditConfig struct {
+	// RetentionDays is how long auth events are kept before the cron job
+	// deletes them; 0 keeps them forever.
+	RetentionDays int
+}
+
+type LoginRiskConfig struct {
+	// GeoIPDB is a MaxMind GeoIP2 or GeoLite2 City database file used to
+	// locate sign-ins. Without one, alerts show no location and impossible
+	// travel is not detected.
+	GeoIPDB string
+
+	// MaxTravelSpeed in km/h is the fastest a user can plausibly move between
+	// two consecutive sign-ins.
+	MaxTravelSpeed float64
+
+	// StepUp requires an emailed code to finish sign-ins whose risk score
+	// reaches StepUpScore (0 to 100).
+	StepUp      bool
+	StepUpScore int
+}
+
+const (
+	defaultEncryptionKey   = "change-me-encryption-key"
+	minEncryptionKeyLength = 32
+)
+
+// Registration modes
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+)
+
+type RegistrationConfig struct {
+	// Mode is RegistrationOpen to let anyone register, or RegistrationInvite
+	// to require an invitation code.
+	Mode string
+	// InvitesPerUser is how many unused invitations each user may have open
+	// at a time; 0 leaves inviting to staff with invitations:manage.
+	InvitesPerUser int
+}
+
+type AccountConfig struct {
+	// DeletionGraceDays is how long a deleted account can be restored before
+	// the cron job anonymizes it; 0 anonymizes it on the job's next run.
+	DeletionGraceDays int
+}
+
+type CORSConfig struct {
+	// AllowOrigins may call the API from a browser with credentials, and are
+	// the only origins the refresh cookie endpoint accepts requests from.
+	AllowOrigins []string
+	// MaxAge is how long browsers may cache preflight responses.
+	MaxAge time.Duration
+}
+
 type AllConfig struct {
-	PortAddress string
-	DB          DBConfig
-	SMTP        SMTPConfig
+	PortAddress  string
+	DB           DBConfig
+	SMTP         SMTPConfig
+	SMS          SMSConfig
+	Google       OAuthConfig
+	RateLimit    RateLimitConfig
+	Lockout      LockoutConfig
+	Audit        AuditConfig
+	LoginRisk    LoginRiskConfig
+	CORS         CORSConfig
+	Account      AccountConfig
+	Registration RegistrationConfig
 }
 
 func AppConfig() AllConfig {
@@ -75,23 +183,204 @@ func AppConfig() AllConfig {
 	flag.StringVar(&cfg.SMTP.Password, "smtp-password", "password", "SMTP password")
 	flag.StringVar(&cfg.SMTP.From, "smtp-from", "noreply@example.com", "SMTP from address")
 
+	// SMS config
+	flag.StringVar(&cfg.SMS.LogFile, "sms-log-file", "", "File to append outgoing text messages to during development (empty logs only their recipients)")
+
+	// Google OAuth2 / OpenID Connect config
+	flag.StringVar(&cfg.Google.ClientID, "google-client-id", "", "Google OAuth client ID")
+	flag.StringVar(&cfg.Google.ClientSecret, "google-client-secret", "", "Google OAuth client secret")
+	flag.StringVar(&cfg.Google.RedirectURL, "google-redirect-url", "http://localhost:8080/api/v1/auth/google-callback", "Google OAuth redirect URL")
+	flag.StringVar(&cfg.Google.Issuer, "google-issuer", "https://accounts.google.com", "Google OpenID Connect issuer URL (point at a fake OIDC server in tests)")
+
+	// Rate limit config
+	flag.Func("rate-limit-store", "Rate limit store (memory|postgres, default memory)", func(s string) error {
+		switch s {
+		case "memory", "postgres":
+			cfg.RateLimit.Store = s
+			return nil
+		default:
+			return fmt.Errorf("invalid rate limit store %q, must be one of: memory, postgres", s)
+		}
+	})
+	flag.IntVar(&cfg.RateLimit.IPLimit, "rate-limit-ip", 20, "Requests per client IP allowed on each auth endpoint within -rate-limit-ip-window")
+	flag.DurationVar(&cfg.RateLimit.IPWindow, "rate-limit-ip-window", time.Minute, "Sliding window of the per IP rate limit")
+	flag.IntVar(&cfg.RateLimit.AccountLimit, "rate-limit-account", 10, "Requests per email or user ID allowed on each auth endpoint within -rate-limit-account-window")
+	flag.DurationVar(&cfg.RateLimit.AccountWindow, "rate-limit-account-window", 15*time.Minute, "Sliding window of the per account rate limit")
+
+	// Account lockout config
+	flag.IntVar(&cfg.Lockout.Threshold, "lockout-threshold", 5, "Failed logins in a row that lock an account")
+	flag.DurationVar(&cfg.Lockout.Duration, "lockout-duration", 15*time.Minute, "How long the first lock lasts; each repeat lock without a successful login doubles it")
+	flag.DurationVar(&cfg.Lockout.MaxDuration, "lockout-max-duration", 24*time.Hour, "Longest lock repeat lockouts can reach")
+
+	// Audit log config
+	flag.IntVar(&cfg.Audit.RetentionDays, "auth-events-retention-days", 365, "Days to keep auth events before cmd/cronjob deletes them (0 keeps them forever)")
+
+	// New device alerts and sign-in risk scoring config
+	flag.StringVar(&cfg.LoginRisk.GeoIPDB, "geoip-db", "", "MaxMind GeoIP2/GeoLite2 City database file used to locate sign-ins (empty disables locations and impossible travel checks)")
+	flag.Float64Var(&cfg.LoginRisk.MaxTravelSpeed, "login-max-travel-speed", 1000, "Fastest plausible travel in km/h between two sign-ins; faster is impossible travel")
+	flag.BoolVar(&cfg.LoginRisk.StepUp, "login-step-up", false, "Require an emailed code to finish sign-ins whose risk score reaches -login-step-up-score")
+	flag.IntVar(&cfg.LoginRisk.StepUpScore, "login-step-up-score", 60, "Risk score (1-100) at which sign-ins need an emailed code when -login-step-up is set")
+
+	// Registration config
+	flag.StringVar(&cfg.Registration.Mode, "registration-mode", RegistrationOpen, "Who may register (open|invite); invite requires an invitation code")
+	flag.IntVar(&cfg.Registration.InvitesPerUser, "invites-per-user", 5, "Unused invitations each user may have open at a time (0 leaves inviting to staff)")
+
+	// Account deletion config
+	flag.IntVar(&cfg.Account.DeletionGraceDays, "account-deletion-grace-days", 30, "Days a deleted account can be restored before cmd/cronjob anonymizes it")
+
+	// CORS config
+	corsOrigins := flag.String("cors-origins", "", "Comma separated origins allowed to call the API with credentials (default: the origin of -frontend-url); production and staging need https")
+	flag.DurationVar(&cfg.CORS.MaxAge, "cors-max-age", 10*time.Minute, "How long browsers may cache CORS preflight responses")
+
+	// Password hashing (argon2id) config; hashes made with other parameters
+	// are upgraded when their users next log in
+	argon2Memory := flag.Uint("argon2-memory", uint(utils.PasswordParams.Memory), "Argon2id memory cost in KiB for password hashes")
+	argon2Iterations := flag.Uint("argon2-iterations", uint(utils.PasswordParams.Iterations), "Argon2id iterations for password hashes")
+	argon2Parallelism := flag.Uint("argon2-parallelism", uint(utils.PasswordParams.Parallelism), "Argon2id parallelism for password hashes")
+
+	// Password policy for new passwords; existing passwords keep working
+	flag.IntVar(&utils.PasswordRules.MinLength, "password-min-length", utils.PasswordRules.MinLength, "Minimum password length")
+	passwordRequire := flag.String("password-require", strings.Join(utils.PasswordRules.RequiredClasses, ","), "Comma separated character classes passwords must contain (lower,upper,letter,digit,symbol)")
+	passwordBannedWords := flag.String("password-banned-words", strings.Join(utils.PasswordRules.BannedWords, ","), "Comma separated words passwords must not contain, besides the user's email")
+	flag.StringVar(&utils.PasswordRules.BreachedDir, "password-breached-dir", "", "Directory of breached password SHA-1 range files, named by 5 character hash prefix with SUFFIX:COUNT lines (empty disables the check)")
+
 	// set constance
 	flag.StringVar(&FrontEndURL, "frontend-url", "http://localhost:3000", "Front end URL")
+	flag.StringVar(&EncryptionKey, "encryption-key", defaultEncryptionKey, fmt.Sprintf("Key used to encrypt secrets at rest, such as TOTP seeds, and to key token hashes and CSRF tokens (at least %d characters outside development)", minEncryptionKeyLength))
 
 	// JWT-Config
 	flag.StringVar(&JWTConfig.Issuer, "jwt-issuer", "myapp.example.com", "JWT Issuer (typically your service domain)")
 	flag.StringVar(&JWTConfig.Secret, "jwt-secret", "1234567890", "JWT Secret (should be a strong, random secret; leave empty to require via env/config)")
 	flag.IntVar(&JWTConfig.TokenExpiry, "jwt-token-expiry", 6, "JWT Access Token Expiry in hours")
 	flag.IntVar(&JWTConfig.RefreshExpiry, "jwt-refresh-expiry", 30, "JWT Refresh Token Expiry in days")
+	flag.IntVar(&JWTConfig.ImpersonationExpiry, "jwt-impersonation-expiry", 30, "Expiry in minutes of access tokens issued to support staff impersonating a user")
 	flag.StringVar(&JWTConfig.RefreshCookieDomain, "jwt-cookie-domain", "localhost", "JWT Cookie Domain (include leading dot for subdomain sharing)")
 	flag.StringVar(&JWTConfig.RefreshCookiePath, "jwt-cookie-path", "/", "JWT Cookie Path")
 	flag.StringVar(&JWTConfig.RefreshCookieName, "jwt-cookie-name", "__r_token", "JWT Cookie Name")
+	flag.StringVar(&JWTConfig.CSRFCookieName, "jwt-csrf-cookie-name", "__r_csrf", "Name of the cookie carrying the CSRF token bound to the refresh token cookie")
+	jwtKeyFiles := flag.String("jwt-key-files", "", "Comma separated PEM files with RS256/EdDSA keys (replaces -jwt-secret signing; public key files only verify)")
+	jwtKeyDir := flag.String("jwt-key-dir", "", "Directory of *.pem JWT keys, named <kid>.pem")
+	jwtSigningKID := flag.String("jwt-signing-kid", "", "ID of the key that signs new tokens (default: the private key whose ID sorts last)")
 
 	flag.Parse()
 
+	// The key guards token hashes, CSRF tokens and TOTP seeds alike, so a
+	// guessable one must never reach a deployed server
+	if !IsDevelopment && (EncryptionKey == defaultEncryptionKey || len(EncryptionKey) < minEncryptionKeyLength) {
+		log.Fatalf("invalid -encryption-key: set a random key of at least %d characters outside development", minEncryptionKeyLength)
+	}
+
 	JWTConfig.Audience = FrontEndURL
+	JWTConfig.CSRFSecret = EncryptionKey
+
+	if *jwtKeyFiles != "" || *jwtKeyDir != "" {
+		keys, err := utils.LoadJWTKeys(splitList(*jwtKeyFiles), *jwtKeyDir, *jwtSigningKID)
+		if err != nil {
+			log.Fatalf("JWT keys: %v", err)
+		}
+		JWTConfig.Keys = keys
+	}
+
+	if JWTConfig.ImpersonationExpiry < 1 {
+		log.Fatalf("invalid -jwt-impersonation-expiry: need at least 1 minute")
+	}
+
+	if *argon2Iterations < 1 || *argon2Parallelism < 1 || *argon2Parallelism > 255 || *argon2Memory < 8**argon2Parallelism {
+		log.Fatalf("invalid argon2 parameters: need iterations >= 1, 1 <= parallelism <= 255 and memory >= 8*parallelism KiB")
+	}
+	utils.PasswordParams.Memory = uint32(*argon2Memory)
+	utils.PasswordParams.Iterations = uint32(*argon2Iterations)
+	utils.PasswordParams.Parallelism = uint8(*argon2Parallelism)
+
+	if utils.PasswordRules.MinLength < 1 || utils.PasswordRules.MinLength > 100 {
+		log.Fatalf("invalid -password-min-length: need 1 to 100")
+	}
+	utils.PasswordRules.RequiredClasses = splitList(*passwordRequire)
+	if err := utils.ValidatePasswordClasses(utils.PasswordRules.RequiredClasses); err != nil {
+		log.Fatalf("invalid -password-require: %v", err)
+	}
+	utils.PasswordRules.BannedWords = splitList(*passwordBannedWords)
+	if dir := utils.PasswordRules.BreachedDir; dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			log.Fatalf("invalid -password-breached-dir: %s is not a directory", dir)
+		}
+	}
+
+	if cfg.Lockout.Threshold < 1 || cfg.Lockout.Duration < time.Second || cfg.Lockout.MaxDuration < cfg.Lockout.Duration {
+		log.Fatalf("invalid lockout config: need -lockout-threshold >= 1, -lockout-duration >= 1s and -lockout-max-duration >= -lockout-duration")
+	}
+
+	if cfg.Audit.RetentionDays < 0 {
+		log.Fatalf("invalid -auth-events-retention-days: must not be negative")
+	}
+
+	if dbFile := cfg.LoginRisk.GeoIPDB; dbFile != "" {
+		if info, err := os.Stat(dbFile); err != nil || info.IsDir() {
+			log.Fatalf("invalid -geoip-db: %s is not a file", dbFile)
+		}
+	}
+	if cfg.LoginRisk.MaxTravelSpeed <= 0 {
+		log.Fatalf("invalid -login-max-travel-speed: must be positive")
+	}
+	if cfg.LoginRisk.StepUpScore < 1 || cfg.LoginRisk.StepUpScore > 100 {
+		log.Fatalf("invalid -login-step-up-score: need 1 to 100")
+	}
+
+	if cfg.Registration.Mode != RegistrationOpen && cfg.Registration.Mode != RegistrationInvite {
+		log.Fatalf("invalid -registration-mode: need open or invite")
+	}
+	if cfg.Registration.InvitesPerUser < 0 {
+		log.Fatalf("invalid -invites-per-user: must not be negative")
+	}
+
+	if cfg.Account.DeletionGraceDays < 0 {
+		log.Fatalf("invalid -account-deletion-grace-days: must not be negative")
+	}
+
+	if *corsOrigins == "" {
+		*corsOrigins = FrontEndURL
+	}
+	for _, origin := range splitList(*corsOrigins) {
+		normalized, err := normalizeOrigin(origin)
+		if err != nil {
+			log.Fatalf("invalid -cors-origins: %v", err)
+		}
+		if (IsProduction || IsStaging) && !strings.HasPrefix(normalized, "https://") {
+			log.Fatalf("invalid -cors-origins: %s must use https outside development", origin)
+		}
+		cfg.CORS.AllowOrigins = append(cfg.CORS.AllowOrigins, normalized)
+	}
+	if cfg.CORS.MaxAge < 0 {
+		log.Fatalf("invalid -cors-max-age: must not be negative")
+	}
+
+	if cfg.RateLimit.Store == "" {
+		cfg.RateLimit.Store = "memory"
+	}
 
 	cfg.PortAddress = fmt.Sprintf(":%d", Port)
 
 	return cfg
 }
+
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// normalizeOrigin reduces a URL such as -frontend-url to its scheme://host[:port]
+// origin, lowercased. Wildcards are rejected, since the origins are allowed to
+// send credentials.
+func normalizeOrigin(value string) (string, error) {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.Contains(parsed.Host, "*") {
+		return "", fmt.Errorf("%q is not an http(s) origin", value)
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), nil
+}
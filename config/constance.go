@@ -7,6 +7,7 @@ import (
 
 var (
 	FrontEndURL   = ""
+	EncryptionKey = ""
 	Port          int
 	IsDevelopment = false
 	IsStaging     = false
@@ -18,8 +19,10 @@ var (
 		Secret:              "your-secret",
 		TokenExpiry:         6,
 		RefreshExpiry:       30,
+		ImpersonationExpiry: 30,
 		RefreshCookieDomain: "your-cookie-domain",
 		RefreshCookiePath:   "your-cookie-path",
 		RefreshCookieName:   "your-cookie-name",
+		CSRFCookieName:      "your-csrf-cookie-name",
 	}
 )
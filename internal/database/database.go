@@ -22,6 +22,6 @@ func CloseDatabase(db *sql.DB) {
 	if err := db.Close(); err != nil {
 		log.Fatalf("Error closing database connection: %v", err)
 	} else {
-		log.Fatalf("Database connection closed successfully")
+		log.Println("Database connection closed successfully")
 	}
 }
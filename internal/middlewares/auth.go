@@ -0,0 +1,303 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+	rbacServices "varaden/server/internal/modules/rbac/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const principalKey = "principal"
+
+// HeaderAPIKey carries an API key on requests from machine clients.
+const HeaderAPIKey = "X-API-Key"
+
+// Principal is the authenticated user attached to a request by Protected.
+type Principal struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	VerifiedEmail bool      `json:"verified_email"`
+	// SessionID is the refresh token family the access token was issued for.
+	// It is uuid.Nil for requests authenticated with an API key.
+	SessionID uuid.UUID `json:"session_id"`
+	// APIKeyID is the API key the request was authenticated with, if any.
+	APIKeyID uuid.NullUUID `json:"api_key_id"`
+	// ImpersonatorID is the staff member acting as the user, if any, so the
+	// frontend can show that the session is impersonated.
+	ImpersonatorID uuid.NullUUID `json:"impersonator_id"`
+	// Roles and Permissions are looked up on every request, so revoking a
+	// role takes effect immediately.
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Protected rejects requests without a valid "Authorization: Bearer" access
+// token and stores the authenticated user as a *Principal in the request locals.
+// It can be attached to a whole group or to individual routes.
+func Protected(db *sql.DB) fiber.Handler {
+	auth := newAuthenticator(db)
+
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+
+		principal, err := auth.bearer(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// ProtectedWithAPIKey works like Protected but also accepts an X-API-Key
+// header. A key acts for the user who created it, limited to the scopes it was
+// given. Use it only on routes machine clients need; account management stays
+// behind Protected so a leaked key cannot take over the account.
+func ProtectedWithAPIKey(db *sql.DB) fiber.Handler {
+	auth := newAuthenticator(db)
+
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+
+		principal, err := auth.bearerOrAPIKey(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// Admin authenticates like ProtectedWithAPIKey and also rejects impersonated
+// requests, as staff use admin endpoints acting as themselves. Attach it to
+// each admin route rather than to the group: group handlers run for every
+// path under the prefix, so unknown paths would get a 401 instead of a 404.
+func Admin(db *sql.DB) fiber.Handler {
+	auth := newAuthenticator(db)
+
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+
+		principal, err := auth.bearerOrAPIKey(ctx, c)
+		if err != nil {
+			return err
+		}
+		if principal.IsImpersonated() {
+			return errImpersonated
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+type authenticator struct {
+	users *userServices.Queries
+	auth  *authServices.Queries
+	rbac  *rbacServices.Queries
+}
+
+func newAuthenticator(db *sql.DB) *authenticator {
+	return &authenticator{
+		users: userServices.New(db),
+		auth:  authServices.New(db),
+		rbac:  rbacServices.New(db),
+	}
+}
+
+// bearerOrAPIKey authenticates the X-API-Key header if there is one, and the
+// access token in the Authorization header otherwise.
+func (a *authenticator) bearerOrAPIKey(ctx context.Context, c *fiber.Ctx) (*Principal, error) {
+	if key := c.Get(HeaderAPIKey); key != "" {
+		return a.apiKey(ctx, key)
+	}
+	return a.bearer(ctx, c)
+}
+
+// bearer authenticates the access token in the Authorization header.
+func (a *authenticator) bearer(ctx context.Context, c *fiber.Ctx) (*Principal, error) {
+	tokenStr, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !found || tokenStr == "" {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Missing or malformed bearer token")
+	}
+
+	claims, err := config.JWTConfig.AccessTokenValidate(tokenStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	// Convert strings to UUIDs
+	userUUID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+	sessionUUID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	// Reject access tokens of sessions that were signed out remotely
+	active, err := a.auth.IsSessionFamilyActive(ctx, sessionUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Session has been revoked")
+	}
+
+	user, err := a.users.GetUserById(ctx, userUUID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+	// Changing the password signs out every token issued before
+	if claims.IssuedBefore(user.PasswordChangedAt) {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	principal, err := a.principal(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	principal.SessionID = sessionUUID
+
+	if claims.ActorID != "" {
+		actorID, err := a.impersonator(ctx, claims.ActorID)
+		if err != nil {
+			return nil, err
+		}
+		principal.ImpersonatorID = uuid.NullUUID{UUID: actorID, Valid: true}
+		c.Set(HeaderImpersonatorID, actorID.String())
+	}
+
+	return principal, nil
+}
+
+// apiKey authenticates an API key. The principal only gets the permissions
+// that are both in the key's scopes and still granted to its owner.
+func (a *authenticator) apiKey(ctx context.Context, key string) (*Principal, error) {
+	prefix, ok := utils.ParseAPIKey(key)
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+
+	apiKey, err := a.auth.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(utils.HashAPIKey(key))) != 1 {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+	if apiKey.RevokedAt.Valid {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "API key has been revoked")
+	}
+	if apiKey.ExpiresAt.Valid && apiKey.ExpiresAt.Time.Before(time.Now()) {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "API key has expired")
+	}
+
+	user, err := a.users.GetUserById(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+
+	principal, err := a.principal(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := strings.Split(apiKey.Scopes, ",")
+	permissions := make([]string, 0, len(principal.Permissions))
+	for _, permission := range principal.Permissions {
+		if slices.Contains(scopes, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	principal.APIKeyID = uuid.NullUUID{UUID: apiKey.ID, Valid: true}
+	// A key never acts with its owner's roles, only with its scopes
+	principal.Roles = []string{}
+	principal.Permissions = permissions
+
+	if err := a.auth.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		return nil, err
+	}
+
+	return principal, nil
+}
+
+// impersonator checks that the staff member named in an impersonation token
+// is still active and allowed to impersonate, so taking the permission away
+// ends their impersonations right away.
+func (a *authenticator) impersonator(ctx context.Context, actor string) (uuid.UUID, error) {
+	actorID, err := uuid.Parse(actor)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired token")
+	}
+
+	user, err := a.users.GetUserById(ctx, actorID)
+	if err != nil || !user.IsActive {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Impersonation has ended")
+	}
+	permissions, err := a.rbac.ListUserPermissions(ctx, actorID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !slices.Contains(permissions, ImpersonatePermission) {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Impersonation has ended")
+	}
+
+	return actorID, nil
+}
+
+// principal checks the user is active and verified and loads their roles and
+// permissions.
+func (a *authenticator) principal(ctx context.Context, user userServices.GetUserByIdRow) (*Principal, error) {
+	if !user.IsActive {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+	if !user.VerifiedEmail {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Email address is not verified")
+	}
+
+	roles, err := a.rbac.ListUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := a.rbac.ListUserPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		VerifiedEmail: user.VerifiedEmail,
+		Roles:         roles,
+		Permissions:   permissions,
+	}, nil
+}
+
+// GetPrincipal returns the authenticated user stored by Protected. It returns a
+// 401 error when the route is not behind Protected or the request is anonymous.
+func GetPrincipal(c *fiber.Ctx) (*Principal, error) {
+	principal, ok := c.Locals(principalKey).(*Principal)
+	if !ok || principal == nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+	return principal, nil
+}
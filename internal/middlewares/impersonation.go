@@ -0,0 +1,36 @@
+package middlewares
+
+import "github.com/gofiber/fiber/v2"
+
+// ImpersonatePermission lets support staff act as another user.
+const ImpersonatePermission = "users:impersonate"
+
+// HeaderImpersonatorID is set on responses to impersonated requests to the ID
+// of the staff member acting as the user.
+const HeaderImpersonatorID = "X-Impersonator-ID"
+
+var errImpersonated = fiber.NewError(fiber.StatusForbidden, "This action is not available while impersonating a user")
+
+// IsImpersonated reports whether a staff member is acting as the principal.
+func (p *Principal) IsImpersonated() bool {
+	return p.ImpersonatorID.Valid
+}
+
+// DenyImpersonation rejects impersonated requests with a 403 error. Attach it
+// to sensitive actions, such as changing the password, email or payout
+// details, that staff must not take on a user's behalf. It must run after
+// Protected.
+func DenyImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, err := GetPrincipal(c)
+		if err != nil {
+			return err
+		}
+
+		if principal.IsImpersonated() {
+			return errImpersonated
+		}
+
+		return c.Next()
+	}
+}
@@ -1,16 +1,26 @@
 package middlewares
 
 import (
+	"strings"
+	"varaden/server/config"
+	"varaden/server/internal/utils"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
 )
 
-func FiberAppMiddlewares(app *fiber.App) {
+func FiberAppMiddlewares(app *fiber.App, corsConfig config.CORSConfig) {
 	app.Use(LoggerConfig())
 	app.Use(helmet.New())
 	app.Use(compress.New())
-	app.Use(cors.New())
+	// Only listed origins may send the refresh token cookie; never a wildcard
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:     strings.Join(corsConfig.AllowOrigins, ","),
+		AllowCredentials: true,
+		ExposeHeaders:    utils.CSRFHeaderName + "," + HeaderImpersonatorID,
+		MaxAge:           int(corsConfig.MaxAge.Seconds()),
+	}))
 	app.Use(RecoverConfig())
 }
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HasRole reports whether the principal has been granted the role.
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasPermission reports whether any of the principal's roles grants the
+// permission.
+func (p *Principal) HasPermission(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
+
+// RequirePermission rejects requests whose principal lacks any of the given
+// permissions with a 403 error. It must run after Protected, e.g.
+//
+//	api.Get("/", middlewares.RequirePermission("users:read"), um.getAllUsers)
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, err := GetPrincipal(c)
+		if err != nil {
+			return err
+		}
+
+		for _, permission := range permissions {
+			if !principal.HasPermission(permission) {
+				return fiber.NewError(fiber.StatusForbidden, "You do not have permission to perform this action")
+			}
+		}
+
+		return c.Next()
+	}
+}
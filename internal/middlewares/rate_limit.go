@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"varaden/server/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// RateLimitRule allows Limit requests per key within a sliding Window.
+type RateLimitRule struct {
+	// Name tells the keys of different rules apart, e.g. "ip" or "email".
+	Name   string
+	Limit  int
+	Window time.Duration
+	// Key returns what the request is counted against; requests it returns
+	// an empty key for are not limited by the rule.
+	Key func(c *fiber.Ctx) string
+}
+
+// RateLimitByIP counts requests per client IP.
+func RateLimitByIP(limit int, window time.Duration) RateLimitRule {
+	return RateLimitRule{
+		Name:   "ip",
+		Limit:  limit,
+		Window: window,
+		Key:    func(c *fiber.Ctx) string { return c.IP() },
+	}
+}
+
+// RateLimitByBodyField counts requests per value of a field of the JSON body,
+// such as "email" or "user_id", compared case-insensitively.
+func RateLimitByBodyField(field string, limit int, window time.Duration) RateLimitRule {
+	return RateLimitRule{
+		Name:   field,
+		Limit:  limit,
+		Window: window,
+		Key: func(c *fiber.Ctx) string {
+			body := make(map[string]any)
+			if err := c.BodyParser(&body); err != nil {
+				return ""
+			}
+			value, ok := body[field].(string)
+			if !ok {
+				return ""
+			}
+			return strings.ToLower(strings.TrimSpace(value))
+		},
+	}
+}
+
+// RateLimitByUser counts requests per authenticated user. It must run after
+// Protected.
+func RateLimitByUser(limit int, window time.Duration) RateLimitRule {
+	return RateLimitRule{
+		Name:   "user",
+		Limit:  limit,
+		Window: window,
+		Key: func(c *fiber.Ctx) string {
+			principal, err := GetPrincipal(c)
+			if err != nil {
+				return ""
+			}
+			return principal.ID.String()
+		},
+	}
+}
+
+// RateLimit rejects requests that exceed any of the rules with a 429 error and
+// a Retry-After header. Limits are kept per scope, so the same rules can guard
+// several endpoints independently. Every response carries RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers for the tightest rule. When
+// the store fails, requests are let through rather than locking everyone out.
+//
+//	auth.Post("/login", middlewares.RateLimit(store, "login", middlewares.RateLimitByIP(20, time.Minute)), am.login)
+func RateLimit(store services.RateLimitStore, scope string, rules ...RateLimitRule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		var tightest *rateLimitResult
+		var retryAfter time.Duration
+
+		for _, rule := range rules {
+			value := rule.Key(c)
+			if value == "" {
+				continue
+			}
+
+			window := now.Truncate(rule.Window)
+			current, previous, err := store.Hit(ctx, rateLimitKey(scope, rule.Name, value), window, rule.Window)
+			if err != nil {
+				log.Errorf("rate limit %s/%s: %v", scope, rule.Name, err)
+				continue
+			}
+
+			result := newRateLimitResult(rule, now.Sub(window), current, previous)
+			if tightest == nil || result.remaining < tightest.remaining {
+				tightest = &result
+			}
+			if result.exceeded && result.retryAfter > retryAfter {
+				retryAfter = result.retryAfter
+			}
+		}
+
+		if tightest != nil {
+			c.Set("RateLimit-Limit", fmt.Sprint(tightest.limit))
+			c.Set("RateLimit-Remaining", fmt.Sprint(tightest.remaining))
+			c.Set("RateLimit-Reset", fmt.Sprint(seconds(tightest.reset)))
+		}
+		if retryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprint(seconds(retryAfter)))
+			return fiber.NewError(fiber.StatusTooManyRequests, "Too many requests. Try again later.")
+		}
+
+		return c.Next()
+	}
+}
+
+// rateLimitKey hashes the counted value so store keys have a fixed length and
+// do not hold emails or IPs in clear.
+func rateLimitKey(scope, rule, value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return scope + ":" + rule + ":" + hex.EncodeToString(sum[:16])
+}
+
+type rateLimitResult struct {
+	limit      int
+	remaining  int
+	reset      time.Duration
+	exceeded   bool
+	retryAfter time.Duration
+}
+
+// newRateLimitResult applies the sliding window approximation: the previous
+// window's hits count in proportion to how much of it the sliding window still
+// covers, elapsed being the time since the current window started.
+func newRateLimitResult(rule RateLimitRule, elapsed time.Duration, current, previous int64) rateLimitResult {
+	limit := float64(rule.Limit)
+	window := float64(rule.Window)
+	weight := (window - float64(elapsed)) / window
+	count := float64(previous)*weight + float64(current)
+
+	result := rateLimitResult{
+		limit:     rule.Limit,
+		remaining: max(0, int(limit-math.Ceil(count))),
+		reset:     rule.Window - elapsed,
+		exceeded:  count > limit,
+	}
+	if !result.exceeded {
+		return result
+	}
+
+	// Wait until one more request fits: first for the previous window's hits
+	// to slide out, and if the current window alone is full, into the next.
+	if float64(current)+1 <= limit {
+		result.retryAfter = time.Duration(window*(1-(limit-float64(current)-1)/float64(previous))) - elapsed
+	} else {
+		next := 0.0
+		if limit-1 < float64(current) {
+			next = window * (1 - (limit-1)/float64(current))
+		}
+		result.retryAfter = rule.Window - elapsed + time.Duration(next)
+	}
+	result.retryAfter = max(result.retryAfter, time.Second)
+
+	return result
+}
+
+// seconds rounds d up to whole seconds for the rate limit headers.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -4,34 +4,87 @@ import (
 	"database/sql"
 	"varaden/server/config"
 	authServices "varaden/server/internal/modules/auth/services"
+	rbacServices "varaden/server/internal/modules/rbac/services"
 	userServices "varaden/server/internal/modules/user/services"
 	"varaden/server/internal/services"
 	"varaden/server/internal/utils"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 )
 
 type AuthModule struct {
-	db       *sql.DB
-	route    fiber.Router
-	validate *validator.Validate
-	email    services.EmailService
-	token    *authServices.Queries
-	user     *userServices.Queries
-	jwt      *utils.JWTConfig
+	db        *sql.DB
+	route     fiber.Router
+	admin     fiber.Router
+	adminOnly fiber.Handler
+	validate  *validator.Validate
+	email     services.EmailService
+	sms       services.SMSService
+	limiter   services.RateLimitStore
+	geoip     services.GeoIPService
+	limits    config.RateLimitConfig
+	lockout   config.LockoutConfig
+	risk      config.LoginRiskConfig
+	cors      config.CORSConfig
+	signup    config.RegistrationConfig
+	queries   *authServices.Queries
+	user      *userServices.Queries
+	rbac      *rbacServices.Queries
+	jwt       *utils.JWTConfig
+	google    *oidcClient
+	passkeys  *webauthn.WebAuthn
 }
 
-func RegisterAuthModule(route fiber.Router, db *sql.DB, emailService services.EmailService) *AuthModule {
+// Deps are the services and settings the auth module is built with.
+type Deps struct {
+	DB *sql.DB
+	// Admin is the shared /admin group; AdminOnly guards each route on it
+	Admin        fiber.Router
+	AdminOnly    fiber.Handler
+	Email        services.EmailService
+	SMS          services.SMSService
+	RateLimits   services.RateLimitStore
+	GeoIP        services.GeoIPService
+	Google       config.OAuthConfig
+	RateLimit    config.RateLimitConfig
+	Lockout      config.LockoutConfig
+	LoginRisk    config.LoginRiskConfig
+	CORS         config.CORSConfig
+	Registration config.RegistrationConfig
+}
+
+func RegisterAuthModule(route fiber.Router, deps Deps) *AuthModule {
 	jwtConfig := config.JWTConfig
 
+	// Passkeys are optional; their endpoints respond 503 when unavailable
+	passkeys, err := newWebAuthn(config.FrontEndURL)
+	if err != nil {
+		log.Warnf("passkeys disabled: %v", err)
+	}
+
 	return &AuthModule{
-		db:       db,
-		route:    route,
-		email:    emailService,
-		validate: utils.Validator(),
-		token:    authServices.New(db),
-		user:     userServices.New(db),
-		jwt:      jwtConfig,
+		db:        deps.DB,
+		route:     route,
+		admin:     deps.Admin,
+		adminOnly: deps.AdminOnly,
+		email:     deps.Email,
+		sms:       deps.SMS,
+		limiter:   deps.RateLimits,
+		geoip:     deps.GeoIP,
+		limits:    deps.RateLimit,
+		lockout:   deps.Lockout,
+		risk:      deps.LoginRisk,
+		cors:      deps.CORS,
+		signup:    deps.Registration,
+		validate:  utils.Validator(),
+		queries:   authServices.New(deps.DB),
+		user:      userServices.New(deps.DB),
+		rbac:      rbacServices.New(deps.DB),
+		jwt:       jwtConfig,
+		google:    newOIDCClient(deps.Google),
+		passkeys:  passkeys,
 	}
 }
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"varaden/server/config"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// The tests run against a real database, named by TEST_DATABASE_URL. Each test
+// gets a schema of its own with every module's migrations applied.
+const testFrontEndURL = "https://app.varaden.test"
+
+func TestMain(m *testing.M) {
+	config.IsDevelopment = true
+	config.FrontEndURL = testFrontEndURL
+	config.EncryptionKey = "test-encryption-key-of-32-characters"
+	config.JWTConfig.Issuer = "api.varaden.test"
+	config.JWTConfig.Audience = testFrontEndURL
+	config.JWTConfig.Secret = "test-jwt-secret"
+	config.JWTConfig.CSRFSecret = config.EncryptionKey
+
+	os.Exit(m.Run())
+}
+
+// testDB returns a connection to a fresh, migrated schema that is dropped when
+// the test ends.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connConfig.RuntimeParams["search_path"] = schema
+	db := stdlib.OpenDB(*connConfig)
+	t.Cleanup(func() { db.Close() })
+
+	migrate(t, db)
+
+	return db
+}
+
+// migrate applies the Up section of every module's migrations, in version
+// order like goose does.
+func migrate(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	files, err := filepath.Glob("../*/migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return filepath.Base(files[i]) < filepath.Base(files[j])
+	})
+
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		up, _, _ := strings.Cut(string(raw), "-- +goose Down")
+		var statements []string
+		for _, line := range strings.Split(up, "\n") {
+			if !strings.HasPrefix(line, "-- +goose") {
+				statements = append(statements, line)
+			}
+		}
+
+		if _, err := db.Exec(strings.Join(statements, "\n")); err != nil {
+			t.Fatalf("migrate %s: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+type testEmailService struct{}
+
+func (testEmailService) SendEmail(to, subject, body string) error {
+	return nil
+}
+
+type testSMSService struct{}
+
+func (testSMSService) SendSMS(to, body string) error {
+	return nil
+}
+
+// newTestApp serves the auth module on a fresh database.
+func newTestApp(t *testing.T, google config.OAuthConfig) (*fiber.App, *AuthModule) {
+	t.Helper()
+
+	db := testDB(t)
+	geoip, err := services.NewGeoIPService(&config.LoginRiskConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := config.RateLimitConfig{
+		IPLimit:       1000,
+		IPWindow:      time.Minute,
+		AccountLimit:  1000,
+		AccountWindow: time.Minute,
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+	v1Group := app.Group("/api/v1")
+	am := RegisterAuthModule(v1Group, Deps{
+		DB:           db,
+		Admin:        v1Group.Group("/admin"),
+		AdminOnly:    middlewares.Admin(db),
+		Email:        testEmailService{},
+		SMS:          testSMSService{},
+		RateLimits:   services.NewRateLimitStore(&limits, nil),
+		GeoIP:        geoip,
+		Google:       google,
+		RateLimit:    limits,
+		Registration: config.RegistrationConfig{Mode: config.RegistrationOpen},
+	})
+	am.SetupRoutes()
+
+	// Let background work, such as sign-in alerts, finish before the schema goes
+	t.Cleanup(config.SW.Wait)
+
+	return app, am
+}
+
+// createUser adds a user who signs in without a password.
+func createUser(t *testing.T, am *AuthModule, email string, verified bool) uuid.UUID {
+	t.Helper()
+
+	user, err := am.user.CreateOAuthUser(context.Background(), userServices.CreateOAuthUserParams{
+		Email:         email,
+		Name:          "Test User",
+		VerifiedEmail: verified,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+// signIn starts a session for the user and returns its access token.
+func signIn(t *testing.T, am *AuthModule, userID uuid.UUID) string {
+	t.Helper()
+
+	session, err := am.queries.CreateSession(context.Background(), authServices.CreateSessionParams{
+		FamilyID:        uuid.New(),
+		UserID:          userID,
+		UserAgent:       "test",
+		IpAddress:       "127.0.0.1",
+		AuthenticatedAt: time.Now(),
+		ExpiresAt:       time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := am.jwt.GenerateToken(userID, session.ID, session.FamilyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.Token
+}
+
+// doRequest runs a request through the app and returns the response and its body.
+func doRequest(t *testing.T, app *fiber.App, req *http.Request) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
@@ -2,26 +2,30 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"time"
 	"varaden/server/config"
+	"varaden/server/internal/middlewares"
 	authServices "varaden/server/internal/modules/auth/services"
 	userServices "varaden/server/internal/modules/user/services"
 	"varaden/server/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/google/uuid"
 )
 
 // Register a new user
 //
 //	@Summary		Register new user
-//	@Description	Register a new user with email and password. A 6-digit verification code will be sent to the provided email.
+//	@Description	Register a new user with email and password. A 6-digit verification code will be sent to the provided email. An invitation code is required when registration is invite-only, and is checked whenever one is given.
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		registerData				true	"Registration payload"
 //	@Success		200		{object}	userServices.CreateUserRow	"User created successfully. Check email for verification code."
-//	@Failure		400		{object}	utils.CommonError			"Bad Request: Invalid input data"
+//	@Failure		400		{object}	utils.CommonError			"Bad Request: Invalid input data or invitation code"
+//	@Failure		403		{object}	utils.CommonError			"Forbidden: Invitation required, or for a different email"
 //
 //	@Router			/auth/register [post]
 func (am *AuthModule) register(c *fiber.Ctx) error {
@@ -37,6 +41,14 @@ func (am *AuthModule) register(c *fiber.Ctx) error {
 	if err := am.validate.Struct(req); err != nil {
 		return err
 	}
+	if err := utils.PasswordRules.Check("registerData.Password", req.Password, req.Email); err != nil {
+		return err
+	}
+
+	invitation, err := am.checkInvitation(ctx, c, req.InviteCode, req.Email)
+	if err != nil {
+		return err
+	}
 
 	// Hash password
 	passwordHash, err := utils.HashPassword(req.Password)
@@ -44,30 +56,40 @@ func (am *AuthModule) register(c *fiber.Ctx) error {
 		return err
 	}
 
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	// Create user
-	newUser, err := am.user.CreateUser(ctx, userServices.CreateUserParams{
+	newUser, err := am.user.WithTx(tx).CreateUser(ctx, userServices.CreateUserParams{
 		Email:        req.Email,
 		PasswordHash: passwordHash,
 	})
 	if err != nil {
 		return utils.DuplicateEntryError(err, "email")
 	}
+	if invitation != nil {
+		if err := am.redeemInvitation(ctx, am.queries.WithTx(tx), invitation.ID, newUser.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventRegister, newUser.ID, "password")
 
 	// Create email verification token
 	otp := utils.GenerateRandomNumber()
 
-	newToken, err := am.token.CreateToken(ctx, authServices.CreateTokenParams{
-		UserID:    newUser.ID,
-		Token:     otp,
-		Type:      authServices.TokenTypeEmailVerify,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-	})
-	if err != nil {
+	if err := am.queries.IssueToken(ctx, newUser.ID, authServices.TokenTypeEmailVerify, otp, time.Now().Add(24*time.Hour), sql.NullString{}); err != nil {
 		return err
 	}
 
 	// Send verification email
-	if err := am.SendVerificationEmail(newUser.Email, newToken.Token); err != nil {
+	if err := am.SendVerificationEmail(newUser.Email, otp); err != nil {
 		return err
 	}
 
@@ -79,20 +101,21 @@ func (am *AuthModule) register(c *fiber.Ctx) error {
 // Login user
 //
 //	@Summary		Login user
-//	@Description	Authenticate user with email and password. Returns access token and user info. If email is not verified, sends a new verification code and returns user ID with verified_email=false.
+//	@Description	Authenticate user with email and password. Returns access token and user info. If email is not verified, sends a new verification code and returns user ID with verified_email=false. If two-factor authentication is enabled, returns mfa_required=true and an mfa_token to redeem at /auth/mfa/verify instead of the access token. If the sign-in looks risky and step-up is enabled, emails a code and returns step_up_required=true and a step_up_token to redeem at /auth/login/verify instead. Sign-ins from a new device or location are emailed to the user.
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		registerData			true	"Login credentials (email and password)"
+//	@Param			request	body		loginData				true	"Login credentials (email and password)"
 //	@Success		200		{object}	utils.GenericResponse	"Login successful. Contains user info and access token."
 //	@Failure		400		{object}	utils.CommonError"Bad Request: Invalid input format"\
+//	@Failure		423		{object}	utils.CommonError		"Locked: Too many failed attempts. The message and Retry-After header give the remaining lock time."
 //
 //	@Router			/auth/login [post]
 func (am *AuthModule) login(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
-	req := new(registerData)
+	req := new(loginData)
 
 	// Parse and validate request
 	if err := c.BodyParser(req); err != nil {
@@ -105,44 +128,25 @@ func (am *AuthModule) login(c *fiber.Ctx) error {
 	// Authenticate user
 	user, err := am.user.GetUserByEmail(ctx, req.Email)
 	if err != nil {
+		am.logFailure(ctx, c, eventLogin, uuid.Nil, "unknown_email")
 		return fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
 	}
 	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
 		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
 	}
 	if !user.VerifiedEmail {
-		// Get token
-		getToken, err := am.token.GetTokenByUserId(ctx, user.ID)
-
-		if err == nil && getToken.ExpiresAt.Before(time.Now()) {
-			// Send verification email
-			if err := am.SendVerificationEmail(user.Email, getToken.Token); err != nil {
-				return err
-			}
-			return c.JSON(fiber.Map{
-				"data": fiber.Map{
-					"id":             user.ID,
-					"verified_email": false,
-				},
-			})
-		} else {
-			am.token.DeleteToken(ctx, getToken.ID)
-		}
+		am.logFailure(ctx, c, eventLogin, user.ID, "email_not_verified")
 
-		// Create email verification token
+		// Create email verification token; codes are stored hashed, so a
+		// pending one cannot be sent again
 		otp := utils.GenerateRandomNumber()
-		newToken, err := am.token.CreateToken(ctx, authServices.CreateTokenParams{
-			UserID:    user.ID,
-			Token:     otp,
-			Type:      authServices.TokenTypeEmailVerify,
-			ExpiresAt: time.Now().Add(24 * time.Hour),
-		})
-		if err != nil {
+		if err := am.queries.IssueToken(ctx, user.ID, authServices.TokenTypeEmailVerify, otp, time.Now().Add(24*time.Hour), sql.NullString{}); err != nil {
 			return err
 		}
 
 		// Send verification email
-		if err := am.SendVerificationEmail(user.Email, newToken.Token); err != nil {
+		if err := am.SendVerificationEmail(user.Email, otp); err != nil {
 			return err
 		}
 		return c.JSON(fiber.Map{
@@ -153,13 +157,17 @@ func (am *AuthModule) login(c *fiber.Ctx) error {
 		})
 	}
 
-	if user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()) {
-		return fiber.NewError(fiber.StatusTooManyRequests, "Account locked due to multiple failed login attempts.")
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventLogin, user.ID, "locked")
+		return err
 	}
 
 	// Check password
 	if matched := utils.CheckPasswordHash(req.Password, user.PasswordHash); !matched {
-		am.user.IncrementFailedLogin(ctx, user.ID)
+		am.logFailure(ctx, c, eventLogin, user.ID, "invalid_password")
+		if err := am.recordFailedLogin(ctx, c, user.ID); err != nil {
+			return err
+		}
 		return fiber.NewError(fiber.StatusUnauthorized, "Invalid email or password")
 	}
 
@@ -168,36 +176,29 @@ func (am *AuthModule) login(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Generate JWT tokens
-	tokens, err := am.jwt.GenerateToken(user.ID)
-	if err != nil {
-		return err
+	// Upgrade bcrypt hashes and hashes made with older argon2id parameters
+	if utils.PasswordNeedsRehash(user.PasswordHash) {
+		if err := am.rehashPassword(ctx, user.ID, req.Password); err != nil {
+			return err
+		}
 	}
 
-	// Set refresh token in HTTP-only cookie
-	am.jwt.SetRefreshCookie(c, tokens.RefreshToken)
-
-	return c.JSON(fiber.Map{
-		"data": fiber.Map{
-			"email":          user.Email,
-			"name":           user.Name,
-			"id":             user.ID,
-			"verified_email": user.VerifiedEmail,
-			"aToken":         tokens.Token,
-		},
-	})
+	// Issue tokens, or ask for the second factor
+	return am.completeLogin(ctx, c, user.ID, "password")
 }
 
 // Refresh access token or logout
 //
 //	@Summary		Refresh access token or logout
-//	@Description	Refreshes the access token using a valid refresh token from cookies. If 'logout' is true in the request body, invalidates the session and returns a success message.
+//	@Description	Refreshes the access token using a valid refresh token from cookies and rotates the refresh token. Reusing an already rotated refresh token revokes every session in its family. If 'logout' is true in the request body, revokes the session server-side and returns a success message. Requests must come from an allowed origin and echo the CSRF token from the X-CSRF-Token response header or the CSRF cookie set alongside the refresh token.
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		refreshTokensData		true	"Refresh token request (set 'logout': true to log out)"
-//	@Success		200		{object}	utils.GenericResponse	"Returns new access token and user info, or logout confirmation message"
-//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid request body"
+//	@Param			X-CSRF-Token	header		string					true	"CSRF token bound to the refresh token cookie"
+//	@Param			request			body		refreshTokensData		true	"Refresh token request (set 'logout': true to log out)"
+//	@Success		200				{object}	utils.GenericResponse	"Returns new access token and user info, or logout confirmation message"
+//	@Failure		400				{object}	utils.CommonError		"Bad Request: Invalid request body"
+//	@Failure		403				{object}	utils.CommonError		"Origin not allowed or invalid CSRF token"
 //	@Router			/auth/refresh [post]
 func (am *AuthModule) refreshTokens(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
@@ -213,6 +214,9 @@ func (am *AuthModule) refreshTokens(c *fiber.Ctx) error {
 	}
 
 	if req.Logout {
+		if err := am.revokeRefreshSession(ctx, c); err != nil {
+			return err
+		}
 		am.jwt.GetExpiredRefreshCookie(c)
 		return c.JSON(fiber.Map{
 			"data": fiber.Map{
@@ -222,27 +226,82 @@ func (am *AuthModule) refreshTokens(c *fiber.Ctx) error {
 	}
 
 	refreshTokensData := c.Cookies(config.JWTConfig.RefreshCookieName)
-	userId, err := am.jwt.RefreshTokenValidate(refreshTokensData)
+	claims, err := am.jwt.RefreshTokenValidate(refreshTokensData)
 	if err != nil {
 		return c.JSON(fiber.Map{})
 	}
 
-	// Convert string to UUID
-	userUUID, err := uuid.Parse(userId)
+	// Convert strings to UUIDs
+	userUUID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return c.JSON(fiber.Map{})
+	}
+	sessionUUID, err := uuid.Parse(claims.TokenID)
 	if err != nil {
 		return c.JSON(fiber.Map{})
 	}
 
-	// Authenticate user
+	// Lock the session row so concurrent refreshes cannot rotate it twice
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	session, err := qtx.GetSessionForUpdate(ctx, sessionUUID)
+	if err != nil || session.UserID != userUUID {
+		am.jwt.GetExpiredRefreshCookie(c)
+		return c.JSON(fiber.Map{})
+	}
+	if session.RevokedAt.Valid || session.ExpiresAt.Before(time.Now()) {
+		am.jwt.GetExpiredRefreshCookie(c)
+		return c.JSON(fiber.Map{})
+	}
+
+	// Changing the password signs out every token issued before. This runs
+	// before reuse detection: change-password rotates the current family into
+	// fresh tokens, and a stale token from it is not a sign of theft.
 	user, err := am.user.GetUserById(ctx, userUUID)
-	if err != nil || !user.VerifiedEmail || !user.IsActive {
+	if err != nil {
+		return c.JSON(fiber.Map{})
+	}
+	if claims.IssuedBefore(user.PasswordChangedAt) {
+		am.jwt.GetExpiredRefreshCookie(c)
+		return c.JSON(fiber.Map{})
+	}
+
+	// A token that was already rotated is being replayed: assume it was stolen
+	// and revoke every token in its family.
+	if session.RotatedAt.Valid {
+		if err := qtx.RevokeSessionFamily(ctx, session.FamilyID); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Warnf("Refresh token reuse detected for user %s, session family %s revoked", session.UserID, session.FamilyID)
+		am.logFailure(ctx, c, eventRefreshTokenReuse, session.UserID, "family_revoked")
+		am.jwt.GetExpiredRefreshCookie(c)
 		return c.JSON(fiber.Map{})
 	}
 
-	tokens, err := am.jwt.GenerateToken(user.ID)
+	// Authenticate user
+	if !user.VerifiedEmail || !user.IsActive {
+		return c.JSON(fiber.Map{})
+	}
+
+	// Rotate the refresh token within the same family
+	if err := qtx.RotateSession(ctx, session.ID); err != nil {
+		return err
+	}
+	tokens, err := am.issueSessionTokens(ctx, c, qtx, user.ID, session.FamilyID, session.AuthenticatedAt)
 	if err != nil {
 		return c.JSON(fiber.Map{})
 	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{
 		"data": fiber.Map{
@@ -288,28 +347,17 @@ func (am *AuthModule) forgotPassword(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get existing token and delete it
-	existingToken, err := am.token.GetTokenByUserId(ctx, user.ID)
-	if err == nil {
-		am.token.DeleteToken(ctx, existingToken.ID)
-	}
-
-	// Create password reset token
+	// Create password reset token, replacing any earlier one
 	resetToken := utils.GenerateRandomString(32)
 
-	_, err = am.token.CreateToken(ctx, authServices.CreateTokenParams{
-		UserID:    user.ID,
-		Token:     resetToken,
-		Type:      authServices.TokenTypePasswordReset,
-		ExpiresAt: time.Now().Add(12 * time.Hour),
-	})
-	if err != nil {
+	if err := am.queries.IssueToken(ctx, user.ID, authServices.TokenTypePasswordReset, resetToken, time.Now().Add(12*time.Hour), sql.NullString{}); err != nil {
 		return err
 	}
 
 	if err := am.SendResetPasswordEmail(user.Email, resetToken); err != nil {
 		return err
 	}
+	am.logEvent(ctx, c, eventPasswordResetRequest, user.ID, "")
 
 	return c.JSON(fiber.Map{
 		"data": fiber.Map{"message": "If a user with that email exists, a password reset email has been sent"},
@@ -346,11 +394,21 @@ func (am *AuthModule) resetPassword(c *fiber.Ctx) error {
 	}
 
 	// Get and validate token
-	token, err := am.token.GetTokenByCode(ctx, req.Token)
-	if err != nil || token.ExpiresAt.Before(time.Now()) {
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypePasswordReset || token.ExpiresAt.Before(time.Now()) {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired token")
 	}
-	am.token.DeleteToken(ctx, token.ID)
+
+	// Check the policy before using up the token, so the user can try again
+	user, err := am.user.GetUserById(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+	if err := utils.PasswordRules.Check("resetPasswordData.Password", req.Password, user.Email); err != nil {
+		am.logFailure(ctx, c, eventPasswordReset, user.ID, "password_policy")
+		return err
+	}
+	am.queries.DeleteToken(ctx, token.ID)
 
 	// Hash password
 	passwordHash, err := utils.HashPassword(req.Password)
@@ -366,6 +424,7 @@ func (am *AuthModule) resetPassword(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	am.logEvent(ctx, c, eventPasswordReset, token.UserID, "")
 
 	return c.JSON(fiber.Map{
 		"data": fiber.Map{
@@ -374,6 +433,119 @@ func (am *AuthModule) resetPassword(c *fiber.Ctx) error {
 	})
 }
 
+// Change password
+//
+//	@Summary		Change password
+//	@Description	Changes the current user's password. Every access and refresh token issued before the change stops working, signing out all other devices; this device gets a new access token and refresh cookie.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		changePasswordData		true	"Current password, new password and confirmation"
+//	@Success		200		{object}	utils.GenericResponse	"Password changed. Contains user info and a new access token."
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid input or passwords do not match"
+//	@Failure		401		{object}	utils.CommonError		"Unauthorized: Current password is incorrect"
+//	@Failure		423		{object}	utils.CommonError		"Locked: Too many failed attempts"
+//	@Router			/auth/change-password [post]
+func (am *AuthModule) changePassword(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(changePasswordData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Check if passwords match
+	if req.Password != req.ConfirmPassword {
+		return fiber.NewError(fiber.StatusBadRequest, "Passwords do not match")
+	}
+
+	// Check current password
+	user, err := am.user.GetUserById(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventPasswordChange, user.ID, "locked")
+		return err
+	}
+	if matched := utils.CheckPasswordHash(req.CurrentPassword, user.PasswordHash); !matched {
+		am.logFailure(ctx, c, eventPasswordChange, user.ID, "invalid_password")
+		if err := am.recordFailedLogin(ctx, c, user.ID); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Current password is incorrect")
+	}
+	if req.Password == req.CurrentPassword {
+		return fiber.NewError(fiber.StatusBadRequest, "New password must be different from the current password")
+	}
+	if err := utils.PasswordRules.Check("changePasswordData.Password", req.Password, user.Email); err != nil {
+		am.logFailure(ctx, c, eventPasswordChange, user.ID, "password_policy")
+		return err
+	}
+
+	// Hash password
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The users trigger moves password_changed_at, which voids older tokens
+	if err := am.user.WithTx(tx).UpdatePassword(ctx, userServices.UpdatePasswordParams{
+		PasswordHash: passwordHash,
+		ID:           user.ID,
+	}); err != nil {
+		return err
+	}
+
+	qtx := am.queries.WithTx(tx)
+	if _, err := qtx.RevokeOtherUserSessions(ctx, authServices.RevokeOtherUserSessionsParams{
+		UserID:   user.ID,
+		FamilyID: principal.SessionID,
+	}); err != nil {
+		return err
+	}
+
+	// Keep this device signed in with tokens issued after the change
+	if err := qtx.RotateSessionFamily(ctx, principal.SessionID); err != nil {
+		return err
+	}
+	tokens, err := am.issueSessionTokens(ctx, c, qtx, user.ID, principal.SessionID, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventPasswordChange, user.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"email":          user.Email,
+			"name":           user.Name,
+			"id":             user.ID,
+			"verified_email": user.VerifiedEmail,
+			"aToken":         tokens.Token,
+		},
+	})
+}
+
 // Resend email verification
 //
 //	@Summary		Resend verification email
@@ -397,22 +569,10 @@ func (am *AuthModule) sendVerificationEmail(c *fiber.Ctx) error {
 	if err := am.validate.Struct(req); err != nil {
 		return err
 	}
-	// Get token
-	getToken, err := am.token.GetTokenByUserId(ctx, req.UserID)
-
-	if err == nil {
-		am.token.DeleteToken(ctx, getToken.ID)
-	}
-	// Create email verification token
+	// Create email verification token, replacing any earlier one
 	otp := utils.GenerateRandomNumber()
 
-	newToken, err := am.token.CreateToken(ctx, authServices.CreateTokenParams{
-		UserID:    req.UserID,
-		Token:     otp,
-		Type:      authServices.TokenTypeEmailVerify,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-	})
-	if err != nil {
+	if err := am.queries.IssueToken(ctx, req.UserID, authServices.TokenTypeEmailVerify, otp, time.Now().Add(24*time.Hour), sql.NullString{}); err != nil {
 		return err
 	}
 	// Get user
@@ -421,7 +581,7 @@ func (am *AuthModule) sendVerificationEmail(c *fiber.Ctx) error {
 		return err
 	}
 	// Send verification email
-	if err := am.SendVerificationEmail(user.Email, newToken.Token); err != nil {
+	if err := am.SendVerificationEmail(user.Email, otp); err != nil {
 		return err
 	}
 	return c.JSON(fiber.Map{
@@ -432,7 +592,7 @@ func (am *AuthModule) sendVerificationEmail(c *fiber.Ctx) error {
 // Verify user email with OTP
 //
 //	@Summary		Verify email with OTP
-//	@Description	Verifies the user's email using a 6-digit OTP. On success, marks the email as verified, deletes the OTP, and issues new JWT tokens (access token in response, refresh token in HTTP-only cookie).
+//	@Description	Verifies the user's email using a 6-digit OTP. On success, marks the email as verified, deletes the OTP, and issues new JWT tokens (access token in response, refresh token in HTTP-only cookie). After 5 wrong codes the OTP is voided and a new one must be requested.
 //	@Tags			Auth
 //	@Accept			json
 //	@Produce		json
@@ -454,17 +614,18 @@ func (am *AuthModule) verifyEmail(c *fiber.Ctx) error {
 		return err
 	}
 
-	// Get token
-	getToken, err := am.token.GetToken(ctx, authServices.GetTokenParams{
-		Type:   authServices.TokenTypeEmailVerify,
-		Token:  req.OTP,
-		UserID: req.UserID,
-	})
+	// Check the code; too many wrong ones void it
+	getToken, ok, err := am.checkOTP(ctx, req.UserID, authServices.TokenTypeEmailVerify, req.OTP)
 	if err != nil {
+		return err
+	}
+	if !ok {
+		am.logFailure(ctx, c, eventEmailVerify, req.UserID, "invalid_code")
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid OTP")
 	}
 	if getToken.ExpiresAt.Before(time.Now()) {
-		am.token.DeleteToken(ctx, getToken.ID)
+		am.queries.DeleteToken(ctx, getToken.ID)
+		am.logFailure(ctx, c, eventEmailVerify, req.UserID, "expired")
 		return fiber.NewError(fiber.StatusBadRequest, "OTP has expired. Resend OTP Code.")
 	}
 
@@ -474,40 +635,78 @@ func (am *AuthModule) verifyEmail(c *fiber.Ctx) error {
 	}
 
 	// Delete token
-	if err := am.token.DeleteToken(ctx, getToken.ID); err != nil {
+	if err := am.queries.DeleteToken(ctx, getToken.ID); err != nil {
 		return err
 	}
+	am.logEvent(ctx, c, eventEmailVerify, req.UserID, "")
 
-	// Get user
-	user, err := am.user.GetUserById(ctx, req.UserID)
+	// Issue tokens, or ask for the second factor
+	return am.completeLogin(ctx, c, req.UserID, "email_verify")
+}
+
+// Start Google login
+//
+//	@Summary		Start Google login
+//	@Description	Redirects to Google's consent screen using the authorization-code flow with PKCE. State, nonce and the PKCE verifier are kept in a short-lived HTTP-only cookie.
+//	@Tags			Auth
+//	@Success		307	"Redirect to Google"
+//	@Failure		503	{object}	utils.CommonError	"Google login is not configured"
+//	@Router			/auth/google [get]
+func (am *AuthModule) googleLogin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	authURL, err := am.beginOAuthFlow(ctx, c, uuid.Nil)
 	if err != nil {
 		return err
 	}
 
-	// Generate JWT tokens
-	tokens, _ := am.jwt.GenerateToken(req.UserID)
+	return c.Redirect(authURL, fiber.StatusTemporaryRedirect)
+}
 
-	// Set refresh token in HTTP-only cookie
-	am.jwt.SetRefreshCookie(c, tokens.RefreshToken)
+// Complete Google login
+//
+//	@Summary		Google login callback
+//	@Description	Completes the Google authorization-code flow: checks state, exchanges the code with the PKCE verifier, verifies the ID token against the provider's JWKS and nonce, then signs in the linked user. An existing account with the same verified email is linked; otherwise a new user is created. Tokens are issued like /auth/login. When the flow was started from /auth/identities/google the Google account is linked to the signed-in user instead.
+//	@Tags			Auth
+//	@Produce		json
+//	@Param			code	query		string					true	"Authorization code"
+//	@Param			state	query		string					true	"State returned by the provider"
+//	@Success		200		{object}	utils.GenericResponse	"Login successful. Contains user info and access token."
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid state, code or ID token"
+//	@Failure		409		{object}	utils.CommonError		"Google account or email already linked elsewhere"
+//	@Router			/auth/google-callback [get]
+func (am *AuthModule) googleCallback(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
 
-	return c.JSON(fiber.Map{
-		"data": fiber.Map{
-			"email":  user.Email,
-			"name":   user.Name,
-			"id":     user.ID,
-			"aToken": tokens.Token,
-		},
-	})
-}
+	identity, linkUserID, err := am.completeOAuthFlow(ctx, c)
+	if err != nil {
+		return err
+	}
 
-func (am *AuthModule) googleLogin(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"message": "googleLogin: not implemented",
-	})
-}
+	// Flow started by a signed-in user to link their Google account
+	if linkUserID != uuid.Nil {
+		if err := am.linkIdentity(ctx, linkUserID, googleProvider, identity); err != nil {
+			return err
+		}
+		am.logEvent(ctx, c, eventIdentityLink, linkUserID, googleProvider)
+		return c.JSON(fiber.Map{
+			"data": fiber.Map{
+				"message": "Google account linked successfully",
+			},
+		})
+	}
 
-func (am *AuthModule) googleCallback(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
-		"message": "googleCallback: not implemented",
-	})
+	user, err := am.resolveOAuthUser(ctx, googleProvider, identity)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+
+	// Issue tokens, or ask for the second factor
+	return am.completeLogin(ctx, c, user.ID, googleProvider)
 }
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Restore a deleted account
+//
+//	@Summary		Restore account
+//	@Description	Reactivates an account deleted with DELETE /users/me, using the token from the account deleted email, as long as its data has not been erased yet. The user signs in again afterwards.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		restoreAccountData		true	"Token from the account deleted email"
+//	@Success		200		{object}	utils.GenericResponse	"Account restored"
+//	@Failure		400		{object}	utils.CommonError		"Invalid or expired link"
+//	@Router			/auth/restore-account [post]
+func (am *AuthModule) restoreAccount(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(restoreAccountData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Get and validate token
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypeAccountRestore {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		am.queries.DeleteToken(ctx, token.ID)
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Nothing is restored once the grace period ended, even if the cron job
+	// has not erased the account yet
+	restored, err := am.user.WithTx(tx).RestoreUser(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+	if restored == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if err := am.queries.WithTx(tx).DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventAccountRestore, token.UserID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Account restored. You can sign in again.",
+		},
+	})
+}
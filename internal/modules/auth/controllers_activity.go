@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const defaultEventsLimit = 50
+
+// List account activity
+//
+//	@Summary		List account activity
+//	@Description	Lists the current user's security events, newest first: sign-ins and failed attempts, lockouts, password and email changes, two-factor and passkey changes, and sessions signed out. Pass next_before_id from a response as before_id to get the next page.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Param			query	query		activityQuery			false	"Page"
+//	@Success		200		{object}	utils.GenericResponse	"Events and the cursor of the next page"
+//	@Failure		401		{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/auth/activity [get]
+func (am *AuthModule) listActivity(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(activityQuery)
+	// Parse and validate request
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultEventsLimit
+	}
+
+	events, err := am.queries.ListUserAuthEvents(ctx, authServices.ListUserAuthEventsParams{
+		UserID:   principal.ID,
+		BeforeID: sql.NullInt64{Int64: req.BeforeID, Valid: req.BeforeID > 0},
+		RowLimit: req.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	data := make([]fiber.Map, 0, len(events))
+	for _, event := range events {
+		data = append(data, fiber.Map{
+			"id":         event.ID,
+			"event":      event.Event,
+			"outcome":    event.Outcome,
+			"reason":     event.Reason,
+			"ip_address": event.IpAddress,
+			"user_agent": event.UserAgent,
+			"created_at": event.CreatedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"events":         data,
+			"next_before_id": nextBeforeID(events, req.Limit),
+		},
+	})
+}
+
+// Query auth events
+//
+//	@Summary		Query auth events
+//	@Description	Searches the authentication audit log of every user, newest first. Filters combine; since is inclusive and until exclusive, both RFC 3339. Pass next_before_id from a response as before_id to get the next page. Requires the audit:read permission.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			query	query		authEventsQuery			false	"Filters and page"
+//	@Success		200		{object}	utils.GenericResponse	"Events and the cursor of the next page"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid filter"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Router			/admin/auth-events [get]
+func (am *AuthModule) listAuthEvents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(authEventsQuery)
+	// Parse and validate request
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultEventsLimit
+	}
+
+	events, err := am.queries.ListAuthEvents(ctx, authServices.ListAuthEventsParams{
+		UserID:    parseNullUUID(req.UserID),
+		ActorID:   parseNullUUID(req.ActorID),
+		Event:     sql.NullString{String: req.Event, Valid: req.Event != ""},
+		Outcome:   sql.NullString{String: req.Outcome, Valid: req.Outcome != ""},
+		IpAddress: sql.NullString{String: req.IPAddress, Valid: req.IPAddress != ""},
+		Since:     parseNullTime(req.Since),
+		Until:     parseNullTime(req.Until),
+		BeforeID:  sql.NullInt64{Int64: req.BeforeID, Valid: req.BeforeID > 0},
+		RowLimit:  req.Limit,
+	})
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		events = []authServices.AuthEvent{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"events":         events,
+			"next_before_id": nextBeforeID(events, req.Limit),
+		},
+	})
+}
+
+// nextBeforeID is the cursor of the page after events, or nil on the last page.
+func nextBeforeID(events []authServices.AuthEvent, limit int32) *int64 {
+	if len(events) < int(limit) {
+		return nil
+	}
+	return &events[len(events)-1].ID
+}
+
+// parseNullUUID parses a validated, possibly empty, UUID query parameter.
+func parseNullUUID(value string) uuid.NullUUID {
+	id, err := uuid.Parse(value)
+	return uuid.NullUUID{UUID: id, Valid: err == nil}
+}
+
+// parseNullTime parses a validated, possibly empty, RFC 3339 query parameter.
+// Timestamps are stored in UTC.
+func parseNullTime(value string) sql.NullTime {
+	t, err := time.Parse(time.RFC3339, value)
+	return sql.NullTime{Time: t.UTC(), Valid: err == nil}
+}
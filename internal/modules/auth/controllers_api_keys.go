@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// maxAPIKeys caps the active API keys a user can hold at once.
+const maxAPIKeys = 10
+
+// Create an API key
+//
+//	@Summary		Create API key
+//	@Description	Creates an API key for machine clients, sent in the X-API-Key header. The key acts for the current user, limited to the given scopes, which must be permissions the user has. The key is only returned in this response; store it securely.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		apiKeyData				true	"Name, scopes and optional lifetime in days"
+//	@Success		201		{object}	utils.GenericResponse	"Created key, including the secret"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid input"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Scope not granted to the user"
+//	@Failure		409		{object}	utils.CommonError		"Too many active API keys"
+//	@Router			/auth/api-keys [post]
+func (am *AuthModule) createAPIKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(apiKeyData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// A key cannot do more than its owner
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	for _, scope := range scopes {
+		if !principal.HasPermission(scope) {
+			return fiber.NewError(fiber.StatusForbidden, "You cannot grant a scope you do not have: "+scope)
+		}
+	}
+
+	count, err := am.queries.CountActiveUserAPIKeys(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+	if count >= maxAPIKeys {
+		return fiber.NewError(fiber.StatusConflict, "Too many active API keys. Revoke one first.")
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresInDays), Valid: true}
+	}
+
+	apiKey, err := am.queries.CreateAPIKey(ctx, authServices.CreateAPIKeyParams{
+		UserID:    principal.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashAPIKey(key),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventAPIKeyCreate, principal.ID, apiKey.Prefix)
+
+	data := apiKeyResponse(apiKey)
+	data["key"] = key
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// List API keys
+//
+//	@Summary		List API keys
+//	@Description	Lists the current user's API keys that have not been revoked, with their prefix, scopes, expiry and when they were last used. The keys themselves are never returned.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"API keys"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/auth/api-keys [get]
+func (am *AuthModule) listAPIKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	apiKeys, err := am.queries.ListUserAPIKeys(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	data := make([]fiber.Map, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		data = append(data, apiKeyResponse(apiKey))
+	}
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// Revoke an API key
+//
+//	@Summary		Revoke API key
+//	@Description	Revokes one of the current user's API keys. Requests made with it are rejected immediately.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Param			id	path		string					true	"API key ID"
+//	@Success		200	{object}	utils.GenericResponse	"API key revoked"
+//	@Failure		400	{object}	utils.CommonError		"Bad Request: Invalid API key ID"
+//	@Failure		404	{object}	utils.CommonError		"API key not found"
+//	@Router			/auth/api-keys/{id} [delete]
+func (am *AuthModule) revokeAPIKey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	revoked, err := am.queries.RevokeUserAPIKey(ctx, authServices.RevokeUserAPIKeyParams{
+		ID:     keyID,
+		UserID: principal.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "API key not found")
+	}
+	am.logEvent(ctx, c, eventAPIKeyRevoke, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "API key revoked successfully",
+		},
+	})
+}
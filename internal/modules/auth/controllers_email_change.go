@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	emailChangeExpiry     = 30 * time.Minute
+	emailChangeUndoExpiry = 7 * 24 * time.Hour
+	// Accounts without a password must have signed in this recently
+	emailChangeRecentSignIn = 15 * time.Minute
+)
+
+// Request an email change
+//
+//	@Summary		Request email change
+//	@Description	Starts changing the current user's email address. A confirmation link is sent to the new address; the address only changes once it is confirmed. Accounts with a password must give it. Accounts that sign in only with a linked identity or a passkey leave it out, and must have signed in within the last 15 minutes instead.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		changeEmailData			true	"New email address and, if the account has one, current password"
+//	@Success		200		{object}	utils.GenericResponse	"Confirmation link sent"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid input or same address"
+//	@Failure		401		{object}	utils.CommonError		"Unauthorized: Invalid password or sign-in too old"
+//	@Failure		409		{object}	utils.CommonError		"Email already in use"
+//	@Router			/auth/change-email [post]
+func (am *AuthModule) requestEmailChange(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(changeEmailData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Check password, or a recent sign-in for accounts without one
+	user, err := am.user.GetUserById(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" {
+		recent, err := am.recentlySignedIn(ctx, principal, emailChangeRecentSignIn)
+		if err != nil {
+			return err
+		}
+		if !recent {
+			am.logFailure(ctx, c, eventEmailChangeRequest, user.ID, "stale_sign_in")
+			return fiber.NewError(fiber.StatusUnauthorized, "Sign in again to change your email")
+		}
+	} else if matched := utils.CheckPasswordHash(req.Password, user.PasswordHash); !matched {
+		am.logFailure(ctx, c, eventEmailChangeRequest, user.ID, "invalid_password")
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid password")
+	}
+
+	if strings.EqualFold(req.Email, user.Email) {
+		return fiber.NewError(fiber.StatusBadRequest, "New email must be different from the current email")
+	}
+	if _, err := am.user.GetUserByEmail(ctx, req.Email); err == nil {
+		return fiber.NewError(fiber.StatusConflict, "email already in use")
+	}
+
+	// Only the latest request works
+	changeToken := utils.GenerateRandomString(32)
+
+	if err := am.queries.IssueToken(ctx, user.ID, authServices.TokenTypeEmailChange, changeToken, time.Now().Add(emailChangeExpiry), sql.NullString{String: req.Email, Valid: true}); err != nil {
+		return err
+	}
+
+	if err := am.SendEmailChangeEmail(req.Email, changeToken); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventEmailChangeRequest, user.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "A confirmation link has been sent to the new email address",
+		},
+	})
+}
+
+// Confirm an email change
+//
+//	@Summary		Confirm email change
+//	@Description	Confirms a new email address with the token from the link sent to it and switches the account to it. The old address is notified and gets a link to undo the change.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		emailChangeTokenData	true	"Token from the confirmation link"
+//	@Success		200		{object}	utils.GenericResponse	"Email changed"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid or expired link"
+//	@Failure		409		{object}	utils.CommonError		"Email already in use"
+//	@Router			/auth/change-email/confirm [post]
+func (am *AuthModule) confirmEmailChange(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(emailChangeTokenData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Get and validate token
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypeEmailChange || !token.Target.Valid {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if err := am.queries.DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+
+	user, err := am.user.GetUserById(ctx, token.UserID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if !user.IsActive {
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	// The address may have been taken since the change was requested
+	if err := am.user.WithTx(tx).UpdateEmail(ctx, userServices.UpdateEmailParams{
+		Email: token.Target.String,
+		ID:    user.ID,
+	}); err != nil {
+		return utils.DuplicateEntryError(err, "email")
+	}
+
+	// Let the old address undo the change
+	undoToken := utils.GenerateRandomString(32)
+	if err := qtx.IssueToken(ctx, user.ID, authServices.TokenTypeEmailChangeUndo, undoToken, time.Now().Add(emailChangeUndoExpiry), sql.NullString{String: user.Email, Valid: true}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := am.SendEmailChangedEmail(user.Email, token.Target.String, undoToken); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventEmailChange, user.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Email changed successfully",
+			"email":   token.Target.String,
+		},
+	})
+}
+
+// Undo an email change
+//
+//	@Summary		Undo email change
+//	@Description	Restores the previous email address with the token from the notice sent to it, and signs the account out of every device. Meant for owners whose address was changed without their consent; they should reset their password next.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		emailChangeTokenData	true	"Token from the email change notice"
+//	@Success		200		{object}	utils.GenericResponse	"Email restored"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid or expired link"
+//	@Failure		409		{object}	utils.CommonError		"Email already in use"
+//	@Router			/auth/change-email/undo [post]
+func (am *AuthModule) undoEmailChange(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(emailChangeTokenData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Get and validate token
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypeEmailChangeUndo || !token.Target.Valid {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		am.queries.DeleteToken(ctx, token.ID)
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	if err := am.user.WithTx(tx).UpdateEmail(ctx, userServices.UpdateEmailParams{
+		Email: token.Target.String,
+		ID:    token.UserID,
+	}); err != nil {
+		return utils.DuplicateEntryError(err, "email")
+	}
+
+	// Whoever changed the address may still be signed in
+	if err := qtx.DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+	if err := qtx.DeleteUserTokens(ctx, authServices.DeleteUserTokensParams{
+		UserID: token.UserID,
+		Type:   authServices.TokenTypeEmailChange,
+	}); err != nil {
+		return err
+	}
+	if _, err := qtx.RevokeAllUserSessions(ctx, token.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventEmailChangeUndo, token.UserID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Email restored and all devices signed out. Reset your password if you did not change it.",
+			"email":   token.Target.String,
+		},
+	})
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// List linked identities
+//
+//	@Summary		List linked identities
+//	@Description	Lists the external identity providers linked to the current user.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Linked identities"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/auth/identities [get]
+func (am *AuthModule) listIdentities(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	identities, err := am.queries.ListUserIdentities(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	data := make([]fiber.Map, 0, len(identities))
+	for _, identity := range identities {
+		data = append(data, fiber.Map{
+			"provider":  identity.Provider,
+			"email":     identity.Email,
+			"linked_at": identity.LinkedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// Link a Google account
+//
+//	@Summary		Link Google account
+//	@Description	Starts the Google authorization-code flow for the current user. Redirect the browser to the returned URL; the callback links the Google account instead of signing in.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Google authorization URL"
+//	@Failure		503	{object}	utils.CommonError		"Google login is not configured"
+//	@Router			/auth/identities/google [post]
+func (am *AuthModule) linkGoogle(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	authURL, err := am.beginOAuthFlow(ctx, c, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"url": authURL,
+		},
+	})
+}
+
+// Unlink an identity
+//
+//	@Summary		Unlink identity provider
+//	@Description	Removes a linked identity provider from the current user. The last remaining sign-in method cannot be removed; set a password first via forgot-password.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Param			provider	path		string					true	"Identity provider, e.g. google"
+//	@Success		200			{object}	utils.GenericResponse	"Identity unlinked"
+//	@Failure		404			{object}	utils.CommonError		"Identity not linked"
+//	@Failure		409			{object}	utils.CommonError		"Cannot remove the last sign-in method"
+//	@Router			/auth/identities/{provider} [delete]
+func (am *AuthModule) unlinkIdentity(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	provider := c.Params("provider")
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	// Counting locks the user's row, so concurrent removals run one at a time
+	methods, err := am.loginMethodCount(ctx, tx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	linked, err := qtx.HasUserIdentity(ctx, authServices.HasUserIdentityParams{
+		UserID:   principal.ID,
+		Provider: provider,
+	})
+	if err != nil {
+		return err
+	}
+	if !linked {
+		return fiber.NewError(fiber.StatusNotFound, "Identity not linked")
+	}
+	if methods <= 1 {
+		return fiber.NewError(fiber.StatusConflict, "Cannot remove your only sign-in method. Set a password first.")
+	}
+
+	if _, err := qtx.DeleteUserIdentity(ctx, authServices.DeleteUserIdentityParams{
+		UserID:   principal.ID,
+		Provider: provider,
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventIdentityUnlink, principal.ID, provider)
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Identity unlinked successfully",
+		},
+	})
+}
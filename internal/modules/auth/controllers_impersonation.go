@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Impersonate a user
+//
+//	@Summary		Impersonate user
+//	@Description	Issues a short-lived access token for the given user that names the caller in its act claim, so support staff can see the app as the user does. Responses to requests made with it carry an X-Impersonator-ID header. Changing the user's password, email and other sensitive settings and calling admin endpoints are not allowed with it, and there is no refresh token. Requires the users:impersonate permission and a signed-in session; staff accounts and users with permissions the caller lacks cannot be impersonated.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			id		path		string					true	"User ID"
+//	@Param			request	body		impersonateData			true	"Why the user is impersonated, for the audit log"
+//	@Success		200		{object}	utils.GenericResponse	"Access token for the user"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Cannot impersonate this user"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Missing permission, staff account, or target has permissions the caller lacks"
+//	@Failure		404		{object}	utils.CommonError		"User not found"
+//	@Router			/admin/users/{id}/impersonate [post]
+func (am *AuthModule) startImpersonation(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+	// An API key is not a person who can be held to account
+	if principal.APIKeyID.Valid {
+		return fiber.NewError(fiber.StatusForbidden, "Impersonation needs a signed-in session")
+	}
+
+	req := new(impersonateData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if userID == principal.ID {
+		return fiber.NewError(fiber.StatusBadRequest, "You cannot impersonate yourself")
+	}
+	user, err := am.user.GetUserById(ctx, userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if !user.IsActive || !user.VerifiedEmail {
+		return fiber.NewError(fiber.StatusBadRequest, "Only active users with a verified email can be impersonated")
+	}
+
+	// Acting as a user with permissions the caller lacks would hand them over,
+	// so only users who can do no more than the caller can be impersonated.
+	// That rules out fellow staff, whose permissions include impersonation.
+	permissions, err := am.rbac.ListUserPermissions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(permissions, middlewares.ImpersonatePermission) {
+		return fiber.NewError(fiber.StatusForbidden, "Staff accounts cannot be impersonated")
+	}
+	for _, permission := range permissions {
+		if !principal.HasPermission(permission) {
+			return fiber.NewError(fiber.StatusForbidden, "You cannot impersonate a user with permissions you do not have")
+		}
+	}
+
+	// The impersonation gets a session of its own, so it shows among the
+	// user's sessions and can be ended like any other
+	expiresAt := time.Now().Add(time.Duration(am.jwt.ImpersonationExpiry) * time.Minute)
+	session, err := am.queries.CreateSession(ctx, authServices.CreateSessionParams{
+		FamilyID:        uuid.New(),
+		UserID:          user.ID,
+		UserAgent:       utils.Truncate(c.Get(fiber.HeaderUserAgent), 512),
+		IpAddress:       c.IP(),
+		AuthenticatedAt: time.Now(),
+		ExpiresAt:       expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	accessToken, err := am.jwt.GenerateImpersonationToken(user.ID, session.FamilyID, principal.ID)
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventImpersonationStart, user.ID, req.Reason)
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"email":           user.Email,
+			"name":            user.Name,
+			"id":              user.ID,
+			"verified_email":  user.VerifiedEmail,
+			"aToken":          accessToken,
+			"expires_at":      expiresAt,
+			"impersonator_id": principal.ID,
+		},
+	})
+}
+
+// Stop impersonating
+//
+//	@Summary		Stop impersonating
+//	@Description	Ends the impersonation the access token was issued for, revoking the token.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Impersonation ended"
+//	@Failure		400	{object}	utils.CommonError		"Bad Request: Not impersonating a user"
+//	@Router			/auth/impersonation/stop [post]
+func (am *AuthModule) stopImpersonation(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+	if !principal.IsImpersonated() {
+		return fiber.NewError(fiber.StatusBadRequest, "Not impersonating a user")
+	}
+
+	if err := am.queries.RevokeSessionFamily(ctx, principal.SessionID); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventImpersonationStop, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Impersonation ended",
+		},
+	})
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"varaden/server/config"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+const (
+	// invitationsPermission lets staff invite many users with one code and
+	// see everyone's invitations.
+	invitationsPermission = "invitations:manage"
+
+	invitationCodeLength        = 12
+	defaultInvitationExpiryDays = 14
+	defaultInvitationsLimit     = 50
+)
+
+// Create an invitation
+//
+//	@Summary		Create invitation
+//	@Description	Creates a single-use invitation code the current user can share, optionally only valid for one email address, which is then emailed the code. Each user may have a limited number of unused invitations open at a time.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		invitationData			true	"Invitee email and expiry, both optional"
+//	@Success		201		{object}	utils.GenericResponse	"Invitation with its code"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Inviting is left to staff"
+//	@Failure		409		{object}	utils.CommonError		"Too many open invitations"
+//	@Router			/auth/invitations [post]
+func (am *AuthModule) createInvitation(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(invitationData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	if am.signup.InvitesPerUser == 0 {
+		return fiber.NewError(fiber.StatusForbidden, "Invitations are sent by staff only")
+	}
+	count, err := am.queries.CountOpenUserInvitations(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+	if count >= int64(am.signup.InvitesPerUser) {
+		return fiber.NewError(fiber.StatusConflict, "Too many open invitations. Wait for one to be used or expire.")
+	}
+
+	return am.issueInvitation(ctx, c, principal, req.Email, 1, req.ExpiresInDays)
+}
+
+// Create an invitation as staff
+//
+//	@Summary		Create invitation (admin)
+//	@Description	Creates an invitation code that can be used up to max_uses times, such as for everyone joining the beta in a new city. An invitation for one email address can only be used once. Requires the invitations:manage permission.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			request	body		adminInvitationData		true	"Invitee email, uses and expiry, all optional"
+//	@Success		201		{object}	utils.GenericResponse	"Invitation with its code"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Several uses for one email address"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Router			/admin/invitations [post]
+func (am *AuthModule) createAdminInvitation(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(adminInvitationData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	// An email address can only register once
+	if req.Email != "" && req.MaxUses > 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "An invitation for one email address can only be used once")
+	}
+
+	return am.issueInvitation(ctx, c, principal, req.Email, req.MaxUses, req.ExpiresInDays)
+}
+
+// List invitations
+//
+//	@Summary		List invitations
+//	@Description	Lists the invitations the current user created, newest first, with the users who registered with them. Pass next_before and next_before_id from a response as before and before_id to get the next page.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Param			query	query		invitationsQuery		false	"Page"
+//	@Success		200		{object}	utils.GenericResponse	"Invitations and the cursor of the next page"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid query parameters"
+//	@Router			/auth/invitations [get]
+func (am *AuthModule) listInvitations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(invitationsQuery)
+	// Parse and validate request
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	return am.respondInvitations(ctx, c, uuid.NullUUID{UUID: principal.ID, Valid: true}, req.Before, req.BeforeID, req.Limit)
+}
+
+// List everyone's invitations
+//
+//	@Summary		List invitations (admin)
+//	@Description	Lists invitations of every user, or of inviter_id, newest first, with the users who registered with them, which shows who invited whom. Pass next_before and next_before_id from a response as before and before_id to get the next page. Requires the invitations:manage permission.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			query	query		adminInvitationsQuery	false	"Filters and page"
+//	@Success		200		{object}	utils.GenericResponse	"Invitations and the cursor of the next page"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid filter"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Router			/admin/invitations [get]
+func (am *AuthModule) listAllInvitations(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(adminInvitationsQuery)
+	// Parse and validate request
+	if err := c.QueryParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	return am.respondInvitations(ctx, c, parseNullUUID(req.InviterID), req.Before, req.BeforeID, req.Limit)
+}
+
+// issueInvitation creates an invitation from the principal and emails its
+// code to the invitee, if one is named.
+func (am *AuthModule) issueInvitation(ctx context.Context, c *fiber.Ctx, principal *middlewares.Principal, email string, maxUses int32, expiresInDays int) error {
+	if expiresInDays == 0 {
+		expiresInDays = defaultInvitationExpiryDays
+	}
+
+	invitation, err := am.queries.CreateInvitation(ctx, authServices.CreateInvitationParams{
+		Code:      utils.GenerateRandomString(invitationCodeLength),
+		InviterID: principal.ID,
+		Email:     sql.NullString{String: strings.ToLower(email), Valid: email != ""},
+		MaxUses:   maxUses,
+		ExpiresAt: sql.NullTime{Time: time.Now().AddDate(0, 0, expiresInDays), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventInvitationCreate, principal.ID, invitation.ID.String())
+
+	// The code is in the response too, so a lost email is not fatal
+	if invitation.Email.Valid {
+		if err := am.SendInvitationEmail(invitation.Email.String, principal, invitation); err != nil {
+			log.Errorf("failed to send invitation %s email: %v", invitation.ID, err)
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data": invitationResponse(invitation, nil),
+	})
+}
+
+// respondInvitations responds with a page of invitations, of the inviter if
+// given. Invitations created in the same instant are told apart by ID, so a
+// page never skips or repeats one.
+func (am *AuthModule) respondInvitations(ctx context.Context, c *fiber.Ctx, inviterID uuid.NullUUID, before, beforeID string, limit int32) error {
+	if limit == 0 {
+		limit = defaultInvitationsLimit
+	}
+
+	rows, err := am.queries.ListInvitations(ctx, authServices.ListInvitationsParams{
+		InviterID: inviterID,
+		Before:    parseNullTime(before),
+		BeforeID:  parseNullUUID(beforeID),
+		RowLimit:  limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	data := make([]fiber.Map, 0, len(rows))
+	for _, row := range rows {
+		data = append(data, invitationResponse(authServices.Invitation{
+			ID:        row.ID,
+			Code:      row.Code,
+			InviterID: row.InviterID,
+			Email:     row.Email,
+			MaxUses:   row.MaxUses,
+			Uses:      row.Uses,
+			ExpiresAt: row.ExpiresAt,
+			CreatedAt: row.CreatedAt,
+		}, row.Invitees))
+	}
+
+	var nextBefore *time.Time
+	var nextBeforeID *uuid.UUID
+	if len(rows) == int(limit) {
+		nextBefore = &rows[len(rows)-1].CreatedAt
+		nextBeforeID = &rows[len(rows)-1].ID
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"invitations":    data,
+			"next_before":    nextBefore,
+			"next_before_id": nextBeforeID,
+		},
+	})
+}
+
+// checkInvitation finds the invitation a registration uses. Without a code,
+// it is nil, unless registration is invite-only.
+func (am *AuthModule) checkInvitation(ctx context.Context, c *fiber.Ctx, code, email string) (*authServices.Invitation, error) {
+	if code == "" {
+		if am.signup.Mode == config.RegistrationInvite {
+			am.logFailure(ctx, c, eventRegister, uuid.Nil, "invitation_required")
+			return nil, fiber.NewError(fiber.StatusForbidden, "Registration is by invitation only")
+		}
+		return nil, nil
+	}
+
+	invitation, err := am.queries.GetInvitationByCode(ctx, code)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil || invitation.Uses >= invitation.MaxUses ||
+		(invitation.ExpiresAt.Valid && invitation.ExpiresAt.Time.Before(time.Now())) {
+		am.logFailure(ctx, c, eventRegister, uuid.Nil, "invalid_invitation")
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid or expired invitation code")
+	}
+	if invitation.Email.Valid && !strings.EqualFold(invitation.Email.String, email) {
+		am.logFailure(ctx, c, eventRegister, uuid.Nil, "invitation_email_mismatch")
+		return nil, fiber.NewError(fiber.StatusForbidden, "This invitation is for a different email address")
+	}
+
+	return &invitation, nil
+}
+
+// redeemInvitation uses up one use of the invitation for the new user and
+// records who invited them. A concurrent registration may have taken the last
+// use since checkInvitation.
+func (am *AuthModule) redeemInvitation(ctx context.Context, queries *authServices.Queries, invitationID, userID uuid.UUID) error {
+	redeemed, err := queries.RedeemInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if redeemed == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired invitation code")
+	}
+
+	return queries.CreateInvitationRedemption(ctx, authServices.CreateInvitationRedemptionParams{
+		InvitationID: invitationID,
+		UserID:       userID,
+	})
+}
+
+// invitationResponse describes an invitation and, when listed, the users who
+// registered with it.
+func invitationResponse(invitation authServices.Invitation, invitees json.RawMessage) fiber.Map {
+	var email *string
+	if invitation.Email.Valid {
+		email = &invitation.Email.String
+	}
+	var expiresAt *time.Time
+	if invitation.ExpiresAt.Valid {
+		expiresAt = &invitation.ExpiresAt.Time
+	}
+	if invitees == nil {
+		invitees = json.RawMessage("[]")
+	}
+
+	return fiber.Map{
+		"id":         invitation.ID,
+		"code":       invitation.Code,
+		"inviter_id": invitation.InviterID,
+		"email":      email,
+		"max_uses":   invitation.MaxUses,
+		"uses":       invitation.Uses,
+		"expires_at": expiresAt,
+		"created_at": invitation.CreatedAt,
+		"invitees":   invitees,
+	}
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+// jwks serves the public keys access tokens can be verified with, so other
+// services need no shared secret. It is mounted at /.well-known/jwks.json,
+// outside the API base path, and lists no keys when tokens are signed with the
+// HS256 secret.
+//
+// It has no Swagger block on purpose: swag prefixes every documented route
+// with the /api/v1 base path, so the docs would show a URL that does not exist.
+func (am *AuthModule) jwks(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.JSON(fiber.Map{
+		"keys": am.jwt.Keys.JWKS(),
+	})
+}
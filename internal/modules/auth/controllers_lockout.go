@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+const unlockExpiry = 12 * time.Hour
+
+// accountLocked returns the error to answer sign-in attempts with while the
+// account is locked, or nil when it is not. It tells the user how long the
+// lock lasts and sets Retry-After to match.
+func accountLocked(c *fiber.Ctx, lockedUntil sql.NullTime) error {
+	if !lockedUntil.Valid {
+		return nil
+	}
+	remaining := time.Until(lockedUntil.Time)
+	if remaining <= 0 {
+		return nil
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	return fiber.NewError(fiber.StatusLocked, fmt.Sprintf("Account locked due to multiple failed login attempts. Try again in %s, or use the unlock link sent to your email.", formatLockTime(remaining)))
+}
+
+// recordFailedLogin counts a failed sign-in against the user. When it locks
+// the account, the user is emailed an unlock link and the locked error is
+// returned; otherwise the caller answers with its own error.
+func (am *AuthModule) recordFailedLogin(ctx context.Context, c *fiber.Ctx, userID uuid.UUID) error {
+	failed, err := am.user.IncrementFailedLogin(ctx, userServices.IncrementFailedLoginParams{
+		Threshold:      int32(am.lockout.Threshold),
+		LockSeconds:    int64(am.lockout.Duration.Seconds()),
+		MaxLockSeconds: int64(am.lockout.MaxDuration.Seconds()),
+		ID:             userID,
+	})
+	if err != nil {
+		return err
+	}
+	if !failed.Locked {
+		return nil
+	}
+	am.logEvent(ctx, c, eventLockout, userID, "")
+
+	unlockToken := utils.GenerateRandomString(32)
+	if err := am.queries.IssueToken(ctx, userID, authServices.TokenTypeAccountUnlock, unlockToken, time.Now().Add(unlockExpiry), sql.NullString{}); err != nil {
+		return err
+	}
+	// The lock holds either way; the user can still wait it out
+	if err := am.SendAccountLockedEmail(failed.Email, failed.LockedUntil.Time, unlockToken); err != nil {
+		log.Errorf("failed to send account locked email to user %s: %v", userID, err)
+	}
+
+	return accountLocked(c, failed.LockedUntil)
+}
+
+// formatLockTime rounds a lock's remaining time up to whole minutes, or hours
+// for long locks.
+func formatLockTime(d time.Duration) string {
+	minutes := int(math.Ceil(d.Minutes()))
+	switch {
+	case minutes <= 1:
+		return "1 minute"
+	case minutes < 120:
+		return fmt.Sprintf("%d minutes", minutes)
+	default:
+		return fmt.Sprintf("%d hours", int(math.Ceil(d.Hours())))
+	}
+}
+
+// Unlock account
+//
+//	@Summary		Unlock account
+//	@Description	Unlocks an account locked by failed sign-in attempts and sets a new password, using the token from the account locked email. Every device is signed out, as the old password may have been guessed.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		unlockAccountData		true	"Unlock token, new password and confirmation"
+//	@Success		200		{object}	utils.GenericResponse	"Account unlocked and password changed"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid token, expired token, or passwords do not match"
+//	@Router			/auth/unlock-account [post]
+func (am *AuthModule) unlockAccount(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(unlockAccountData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Check if passwords match
+	if req.Password != req.ConfirmPassword {
+		return fiber.NewError(fiber.StatusBadRequest, "Passwords do not match")
+	}
+
+	// Get and validate token
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypeAccountUnlock || token.ExpiresAt.Before(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired token")
+	}
+
+	user, err := am.user.GetUserById(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+	if err := utils.PasswordRules.Check("unlockAccountData.Password", req.Password, user.Email); err != nil {
+		am.logFailure(ctx, c, eventUnlock, user.ID, "password_policy")
+		return err
+	}
+
+	// Hash password
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	if err := qtx.DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+
+	// The users trigger moves password_changed_at, which voids older tokens
+	userTx := am.user.WithTx(tx)
+	if err := userTx.UpdatePassword(ctx, userServices.UpdatePasswordParams{
+		PasswordHash: passwordHash,
+		ID:           user.ID,
+	}); err != nil {
+		return err
+	}
+	if err := userTx.UnlockUser(ctx, user.ID); err != nil {
+		return err
+	}
+	if _, err := qtx.RevokeAllUserSessions(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventUnlock, user.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Account unlocked. Sign in with your new password.",
+		},
+	})
+}
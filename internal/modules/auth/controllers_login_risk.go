@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Complete a risky login
+//
+//	@Summary		Verify sign-in code
+//	@Description	Redeems the step_up_token returned by a login that looked risky, such as one from a new device and country, with the code emailed to the user. Wrong codes count as failed login attempts.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		stepUpVerifyData		true	"Step-up token and emailed code"
+//	@Success		200		{object}	utils.GenericResponse	"Login successful. Contains user info and access token."
+//	@Failure		401		{object}	utils.CommonError		"Invalid code or expired step-up token"
+//	@Failure		423		{object}	utils.CommonError		"Account locked"
+//	@Router			/auth/login/verify [post]
+func (am *AuthModule) verifyStepUp(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(stepUpVerifyData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	claims, err := am.jwt.FlowTokenValidate(req.StepUpToken, stepUpTokenType)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+	sub, _ := claims["sub"].(string)
+	method, _ := claims["method"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+
+	user, err := am.user.GetUserById(ctx, userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventLogin, user.ID, "locked")
+		return err
+	}
+
+	token, ok, err := am.checkOTP(ctx, user.ID, authServices.TokenTypeLoginStepUp, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok || token.ExpiresAt.Before(time.Now()) {
+		am.logFailure(ctx, c, eventLogin, user.ID, "invalid_step_up_code")
+		if err := am.recordFailedLogin(ctx, c, user.ID); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired code")
+	}
+	if err := am.queries.DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+
+	// The code proved access to the user's email, so the sign-in goes ahead
+	// however risky it still looks
+	risk, err := am.assessLogin(ctx, c, user.ID)
+	if err != nil {
+		return err
+	}
+	return am.finishLogin(ctx, c, user, method, risk)
+}
+
+// Report a sign-in
+//
+//	@Summary		Report a sign-in
+//	@Description	Redeems the link from a new sign-in alert email when the user did not sign in. Signs out every device and forgets the reported device, so signing in from it alerts the user again.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		reportLoginData			true	"Token from the alert email"
+//	@Success		200		{object}	utils.GenericResponse	"All devices signed out"
+//	@Failure		400		{object}	utils.CommonError		"Invalid or expired link"
+//	@Router			/auth/report-login [post]
+func (am *AuthModule) reportLogin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(reportLoginData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Get and validate token
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypeLoginAlert || !token.Target.Valid {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		am.queries.DeleteToken(ctx, token.ID)
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	deviceID, err := uuid.Parse(token.Target.String)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	if err := qtx.DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+	if err := qtx.DeleteKnownDevice(ctx, authServices.DeleteKnownDeviceParams{
+		ID:     deviceID,
+		UserID: token.UserID,
+	}); err != nil {
+		return err
+	}
+	if _, err := qtx.RevokeAllUserSessions(ctx, token.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventLoginReport, token.UserID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "All devices signed out. Change your password to keep whoever signed in out.",
+		},
+	})
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const magicLinkExpiry = 15 * time.Minute
+
+// Request a magic sign-in link
+//
+//	@Summary		Request magic sign-in link
+//	@Description	Emails a single-use sign-in link to the provided address if the user exists. To prevent email enumeration, the same success response is returned regardless of whether the email is registered.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		magicLinkData			true	"Email address to send the link to"
+//	@Success		200		{object}	utils.GenericResponse	"Success message (always returned to prevent email enumeration)"
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid email format or missing field"
+//	@Router			/auth/magic-link [post]
+func (am *AuthModule) requestMagicLink(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(magicLinkData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Get user by email
+	user, err := am.user.GetUserByEmail(ctx, req.Email)
+	if err != nil || !user.IsActive {
+		// To prevent email enumeration, return the same response
+		return c.JSON(fiber.Map{
+			"data": fiber.Map{"message": "If a user with that email exists, a sign-in link has been sent"},
+		})
+	}
+
+	// Create magic link token; only the latest link works
+	magicToken := utils.GenerateRandomString(32)
+
+	if err := am.queries.IssueToken(ctx, user.ID, authServices.TokenTypeMagicLink, magicToken, time.Now().Add(magicLinkExpiry), sql.NullString{}); err != nil {
+		return err
+	}
+
+	if err := am.SendMagicLinkEmail(user.Email, magicToken); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventMagicLinkRequest, user.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{"message": "If a user with that email exists, a sign-in link has been sent"},
+	})
+}
+
+// Sign in with a magic link
+//
+//	@Summary		Login with magic link
+//	@Description	Signs in with the token from a magic sign-in link. The token works once, expires after 15 minutes and is void if the password changed after it was sent. Responds like /auth/login, including the two-factor step when it is enabled.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		magicLinkLoginData		true	"Token from the sign-in link"
+//	@Success		200		{object}	utils.GenericResponse	"Login successful. Contains user info and access token."
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: Invalid or expired link"
+//	@Failure		401		{object}	utils.CommonError		"Unauthorized: Account is deactivated"
+//	@Failure		423		{object}	utils.CommonError		"Locked: Too many failed attempts"
+//	@Router			/auth/magic-link/login [post]
+func (am *AuthModule) magicLinkLogin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(magicLinkLoginData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Get and validate token
+	token, err := am.getLinkToken(ctx, req.Token)
+	if err != nil || token.Type != authServices.TokenTypeMagicLink {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if err := am.queries.DeleteToken(ctx, token.ID); err != nil {
+		return err
+	}
+	if token.ExpiresAt.Before(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+
+	user, err := am.user.GetUserById(ctx, token.UserID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	// A password change voids links sent before it
+	if token.CreatedAt.Before(user.PasswordChangedAt) {
+		am.logFailure(ctx, c, eventLogin, user.ID, "stale_magic_link")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventLogin, user.ID, "locked")
+		return err
+	}
+
+	// Opening the link proves the user controls the address
+	if !user.VerifiedEmail {
+		if err := am.user.VerifyUserEmail(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	// Issue tokens, or ask for the second factor
+	return am.completeLogin(ctx, c, user.ID, "magic_link")
+}
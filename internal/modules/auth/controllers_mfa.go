@@ -0,0 +1,379 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"varaden/server/config"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Complete a two-factor login
+//
+//	@Summary		Verify second factor
+//	@Description	Redeems the mfa_token returned by a login with a current TOTP code or an unused recovery code. Codes are accepted from one period before or after the current one and only once. Wrong codes count as failed login attempts.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		mfaVerifyData			true	"MFA token and TOTP or recovery code"
+//	@Success		200		{object}	utils.GenericResponse	"Login successful. Contains user info and access token."
+//	@Failure		401		{object}	utils.CommonError		"Invalid code or expired MFA token"
+//	@Router			/auth/mfa/verify [post]
+func (am *AuthModule) verifyMFA(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(mfaVerifyData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	claims, err := am.jwt.FlowTokenValidate(req.MFAToken, mfaPendingTokenType)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+
+	user, err := am.user.GetUserById(ctx, userID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventLogin, user.ID, "locked")
+		return err
+	}
+
+	mfa, err := am.queries.GetUserMFA(ctx, userID)
+	if err != nil || !mfa.EnabledAt.Valid {
+		return fiber.NewError(fiber.StatusUnauthorized, "Verification expired. Sign in again.")
+	}
+
+	var verified bool
+	if req.Code != "" {
+		verified, err = am.verifyTOTP(ctx, mfa, req.Code)
+	} else {
+		verified, err = am.useRecoveryCode(ctx, userID, req.RecoveryCode)
+	}
+	if err != nil {
+		return err
+	}
+	if !verified {
+		am.logFailure(ctx, c, eventLogin, userID, "invalid_mfa_code")
+		if err := am.recordFailedLogin(ctx, c, userID); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid verification code")
+	}
+
+	method := "totp"
+	if req.Code == "" {
+		method = "recovery_code"
+	}
+	return am.loginResponse(ctx, c, userID, method)
+}
+
+// Two-factor status
+//
+//	@Summary		Two-factor status
+//	@Description	Returns whether two-factor authentication is enabled for the current user and how many recovery codes are left.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Two-factor status"
+//	@Router			/auth/mfa [get]
+func (am *AuthModule) mfaStatus(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	mfa, err := am.queries.GetUserMFA(ctx, principal.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	enabled := err == nil && mfa.EnabledAt.Valid
+
+	remaining, err := am.queries.CountUnusedRecoveryCodes(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"enabled":                  enabled,
+			"recovery_codes_remaining": remaining,
+		},
+	})
+}
+
+// Start TOTP enrollment
+//
+//	@Summary		Start TOTP enrollment
+//	@Description	Generates a new TOTP secret for the current user and returns it with an otpauth:// URI to show as a QR code. Two-factor authentication is enabled only after confirming a first code.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"TOTP secret and otpauth URI"
+//	@Failure		409	{object}	utils.CommonError		"Two-factor authentication is already enabled"
+//	@Router			/auth/mfa/totp/setup [post]
+func (am *AuthModule) setupTOTP(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	secret := utils.GenerateTOTPSecret()
+	encrypted, err := utils.Encrypt(config.EncryptionKey, secret)
+	if err != nil {
+		return err
+	}
+
+	stored, err := am.queries.UpsertPendingMFA(ctx, authServices.UpsertPendingMFAParams{
+		UserID:     principal.ID,
+		TotpSecret: encrypted,
+	})
+	if err != nil {
+		return err
+	}
+	if stored == 0 {
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"secret":      secret,
+			"otpauth_url": utils.TOTPURI(totpIssuer, principal.Email, secret),
+		},
+	})
+}
+
+// Confirm TOTP enrollment
+//
+//	@Summary		Confirm TOTP enrollment
+//	@Description	Enables two-factor authentication once the first code from the authenticator app checks out, and returns single-use recovery codes. The recovery codes are shown only once.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		totpCodeData			true	"Code from the authenticator app"
+//	@Success		200		{object}	utils.GenericResponse	"Recovery codes"
+//	@Failure		400		{object}	utils.CommonError		"Invalid code or no enrollment in progress"
+//	@Router			/auth/mfa/totp/confirm [post]
+func (am *AuthModule) confirmTOTP(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(totpCodeData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	mfa, err := am.queries.GetUserMFA(ctx, principal.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Start two-factor enrollment first")
+	}
+	if mfa.EnabledAt.Valid {
+		return fiber.NewError(fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	verified, err := am.verifyTOTP(ctx, mfa, req.Code)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid verification code")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	if err := qtx.EnableMFA(ctx, principal.ID); err != nil {
+		return err
+	}
+	codes, err := am.replaceRecoveryCodes(ctx, qtx, principal.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventMFAEnable, principal.ID, "totp")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message":        "Two-factor authentication enabled",
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// Regenerate recovery codes
+//
+//	@Summary		Regenerate recovery codes
+//	@Description	Replaces all recovery codes of the current user with a new set. Requires a current TOTP code. The new codes are shown only once.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		totpCodeData			true	"Code from the authenticator app"
+//	@Success		200		{object}	utils.GenericResponse	"Recovery codes"
+//	@Failure		400		{object}	utils.CommonError		"Invalid code or two-factor authentication not enabled"
+//	@Router			/auth/mfa/recovery-codes [post]
+func (am *AuthModule) regenerateRecoveryCodes(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(totpCodeData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	mfa, err := am.queries.GetUserMFA(ctx, principal.ID)
+	if err != nil || !mfa.EnabledAt.Valid {
+		return fiber.NewError(fiber.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	verified, err := am.verifyTOTP(ctx, mfa, req.Code)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid verification code")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	codes, err := am.replaceRecoveryCodes(ctx, am.queries.WithTx(tx), principal.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventRecoveryCodes, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// Disable two-factor authentication
+//
+//	@Summary		Disable two-factor authentication
+//	@Description	Turns off two-factor authentication for the current user and deletes their recovery codes. Requires the account password.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		passwordConfirmData		true	"Current password"
+//	@Success		200		{object}	utils.GenericResponse	"Two-factor authentication disabled"
+//	@Failure		401		{object}	utils.CommonError		"Invalid password"
+//	@Router			/auth/mfa/disable [post]
+func (am *AuthModule) disableMFA(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(passwordConfirmData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	// Check password
+	user, err := am.user.GetUserById(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+	if matched := utils.CheckPasswordHash(req.Password, user.PasswordHash); !matched {
+		am.logFailure(ctx, c, eventMFADisable, user.ID, "invalid_password")
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid password")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	if err := qtx.DeleteUserMFA(ctx, principal.ID); err != nil {
+		return err
+	}
+	if err := qtx.DeleteRecoveryCodes(ctx, principal.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventMFADisable, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Two-factor authentication disabled",
+		},
+	})
+}
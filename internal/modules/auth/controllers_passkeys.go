@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+// Begin passkey registration
+//
+//	@Summary		Begin passkey registration
+//	@Description	Returns the WebAuthn creation options for navigator.credentials.create() and a ceremony token to send back with the result to /auth/passkeys/register/finish.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Creation options and ceremony token"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Failure		503	{object}	utils.CommonError		"Passkeys are not configured"
+//	@Router			/auth/passkeys/register/begin [post]
+func (am *AuthModule) beginPasskeyRegistration(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	passkeys, err := am.getPasskeys()
+	if err != nil {
+		return err
+	}
+
+	user, err := am.loadPasskeyUser(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	// Don't let the same authenticator register twice
+	creation, session, err := passkeys.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return err
+	}
+
+	ceremonyToken, err := am.ceremonyToken(ctx, passkeyRegisterTokenType, session)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"options":        creation,
+			"ceremony_token": ceremonyToken,
+		},
+	})
+}
+
+// Finish passkey registration
+//
+//	@Summary		Finish passkey registration
+//	@Description	Verifies the attestation returned by navigator.credentials.create() and stores the new passkey for the current user.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		passkeyRegisterData		true	"Ceremony token, passkey name and credential"
+//	@Success		200		{object}	utils.GenericResponse	"Passkey registered"
+//	@Failure		400		{object}	utils.CommonError		"Invalid credential"
+//	@Failure		401		{object}	utils.CommonError		"Passkey ceremony expired"
+//	@Failure		409		{object}	utils.CommonError		"Passkey already registered"
+//	@Router			/auth/passkeys/register/finish [post]
+func (am *AuthModule) finishPasskeyRegistration(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(passkeyRegisterData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	passkeys, err := am.getPasskeys()
+	if err != nil {
+		return err
+	}
+
+	session, err := am.ceremonySession(ctx, req.CeremonyToken, passkeyRegisterTokenType)
+	if err != nil {
+		return err
+	}
+
+	user, err := am.loadPasskeyUser(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid passkey credential")
+	}
+
+	// Checks the challenge, origin, RP ID and that the ceremony was started by this user
+	credential, err := passkeys.CreateCredential(user, session, parsed)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Passkey verification failed")
+	}
+
+	passkey, err := am.queries.CreateWebauthnCredential(ctx, authServices.CreateWebauthnCredentialParams{
+		UserID:          principal.ID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      joinTransports(credential.Transport),
+		Aaguid:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		Flags:           int16(credential.Flags.ProtocolValue()),
+		Name:            req.Name,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fiber.NewError(fiber.StatusConflict, "Passkey is already registered")
+		}
+		return err
+	}
+	am.logEvent(ctx, c, eventPasskeyAdd, principal.ID, passkey.Name)
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"id":         passkey.ID,
+			"name":       passkey.Name,
+			"created_at": passkey.CreatedAt,
+		},
+	})
+}
+
+// Begin passkey login
+//
+//	@Summary		Begin passkey login
+//	@Description	Returns the WebAuthn request options for navigator.credentials.get() and a ceremony token to send back with the assertion to /auth/passkeys/login/finish. No email is needed; the authenticator offers the user's passkeys.
+//	@Tags			Auth
+//	@Produce		json
+//	@Success		200	{object}	utils.GenericResponse	"Request options and ceremony token"
+//	@Failure		503	{object}	utils.CommonError		"Passkeys are not configured"
+//	@Router			/auth/passkeys/login/begin [post]
+func (am *AuthModule) beginPasskeyLogin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	passkeys, err := am.getPasskeys()
+	if err != nil {
+		return err
+	}
+
+	assertion, session, err := passkeys.BeginDiscoverableLogin()
+	if err != nil {
+		return err
+	}
+
+	ceremonyToken, err := am.ceremonyToken(ctx, passkeyLoginTokenType, session)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"options":        assertion,
+			"ceremony_token": ceremonyToken,
+		},
+	})
+}
+
+// Finish passkey login
+//
+//	@Summary		Finish passkey login
+//	@Description	Verifies the assertion returned by navigator.credentials.get() and signs the user in. Responds like /auth/login and sets the refresh token cookie.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		passkeyLoginData		true	"Ceremony token and assertion"
+//	@Success		200		{object}	utils.GenericResponse	"Login successful"
+//	@Failure		400		{object}	utils.CommonError		"Invalid credential"
+//	@Failure		401		{object}	utils.CommonError		"Unauthorized: Unknown passkey or failed verification"
+//	@Failure		403		{object}	utils.CommonError		"Email address is not verified"
+//	@Failure		423		{object}	utils.CommonError		"Locked: Too many failed attempts"
+//	@Router			/auth/passkeys/login/finish [post]
+func (am *AuthModule) finishPasskeyLogin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(passkeyLoginData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	passkeys, err := am.getPasskeys()
+	if err != nil {
+		return err
+	}
+
+	session, err := am.ceremonySession(ctx, req.CeremonyToken, passkeyLoginTokenType)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid passkey credential")
+	}
+
+	// Resolve the user from the credential ID and check the user handle matches
+	var passkey authServices.WebauthnCredential
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		passkey, err = am.queries.GetWebauthnCredential(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		return am.loadPasskeyUser(ctx, passkey.UserID)
+	}
+
+	_, credential, err := passkeys.ValidatePasskeyLogin(handler, session, parsed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			am.logFailure(ctx, c, eventLogin, uuid.Nil, "unknown_passkey")
+			return fiber.NewError(fiber.StatusUnauthorized, "Unknown passkey")
+		}
+		am.logFailure(ctx, c, eventLogin, passkey.UserID, "invalid_passkey")
+		return fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+	}
+
+	// A counter that went backwards means the private key may have been copied
+	if credential.Authenticator.CloneWarning {
+		log.Warnf("passkey %s of user %s reported a sign count regression", passkey.ID, passkey.UserID)
+		am.logFailure(ctx, c, eventLogin, passkey.UserID, "passkey_clone_warning")
+		return fiber.NewError(fiber.StatusUnauthorized, "Passkey verification failed")
+	}
+
+	if err := am.queries.UpdateWebauthnCredentialUsage(ctx, authServices.UpdateWebauthnCredentialUsageParams{
+		CredentialID: credential.ID,
+		SignCount:    int64(credential.Authenticator.SignCount),
+		Flags:        int16(credential.Flags.ProtocolValue()),
+	}); err != nil {
+		return err
+	}
+
+	user, err := am.user.GetUserById(ctx, passkey.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+	if !user.VerifiedEmail {
+		am.logFailure(ctx, c, eventLogin, user.ID, "email_not_verified")
+		return fiber.NewError(fiber.StatusForbidden, "Email address is not verified")
+	}
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventLogin, user.ID, "locked")
+		return err
+	}
+
+	// A user-verified passkey is already two factors, so no MFA challenge
+	return am.loginResponse(ctx, c, user.ID, "passkey")
+}
+
+// List passkeys
+//
+//	@Summary		List passkeys
+//	@Description	Lists the passkeys registered by the current user.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Registered passkeys"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/auth/passkeys [get]
+func (am *AuthModule) listPasskeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	passkeys, err := am.queries.ListUserWebauthnCredentials(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	data := make([]fiber.Map, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		var lastUsedAt *time.Time
+		if passkey.LastUsedAt.Valid {
+			lastUsedAt = &passkey.LastUsedAt.Time
+		}
+		data = append(data, fiber.Map{
+			"id":           passkey.ID,
+			"name":         passkey.Name,
+			"last_used_at": lastUsedAt,
+			"created_at":   passkey.CreatedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// Delete a passkey
+//
+//	@Summary		Delete passkey
+//	@Description	Removes one of the current user's passkeys. The last remaining sign-in method cannot be removed.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Param			id	path		string					true	"Passkey ID"
+//	@Success		200	{object}	utils.GenericResponse	"Passkey deleted"
+//	@Failure		404	{object}	utils.CommonError		"Passkey not found"
+//	@Failure		409	{object}	utils.CommonError		"Cannot remove the last sign-in method"
+//	@Router			/auth/passkeys/{id} [delete]
+func (am *AuthModule) deletePasskey(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	passkeyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Passkey not found")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	// Counting locks the user's row, so concurrent removals run one at a time
+	methods, err := am.loginMethodCount(ctx, tx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	found, err := qtx.HasUserWebauthnCredential(ctx, authServices.HasUserWebauthnCredentialParams{
+		ID:     passkeyID,
+		UserID: principal.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fiber.NewError(fiber.StatusNotFound, "Passkey not found")
+	}
+	if methods <= 1 {
+		return fiber.NewError(fiber.StatusConflict, "Cannot remove your only sign-in method. Set a password first.")
+	}
+
+	if _, err := qtx.DeleteUserWebauthnCredential(ctx, authServices.DeleteUserWebauthnCredentialParams{
+		ID:     passkeyID,
+		UserID: principal.ID,
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventPasskeyRemove, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Passkey deleted successfully",
+		},
+	})
+}
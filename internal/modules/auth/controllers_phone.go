@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"varaden/server/internal/middlewares"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Add a phone number
+//
+//	@Summary		Add phone number
+//	@Description	Sends a 6-digit code by SMS to the given number. Numbers are normalized to E.164; local Bangladeshi numbers such as 01712345678 are accepted. Confirm with /auth/phone/verify.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		phoneData				true	"Phone number"
+//	@Success		200		{object}	utils.GenericResponse	"Verification code sent"
+//	@Failure		400		{object}	utils.CommonError		"Invalid phone number"
+//	@Failure		409		{object}	utils.CommonError		"Phone number is used by another account"
+//	@Failure		429		{object}	utils.CommonError		"Too many codes requested"
+//	@Router			/auth/phone [post]
+func (am *AuthModule) addPhone(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(phoneData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	phone, err := utils.NormalizePhone(req.Phone)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid phone number")
+	}
+
+	owner, err := am.user.GetUserByPhone(ctx, sql.NullString{String: phone, Valid: true})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && owner.ID != principal.ID {
+		return fiber.NewError(fiber.StatusConflict, "Phone number is used by another account")
+	}
+
+	if err := am.throttlePhoneOTP(ctx, c, phone); err != nil {
+		return err
+	}
+	if err := am.sendPhoneOTP(ctx, principal.ID, phone); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"phone":   phone,
+			"message": "Verification code sent",
+		},
+	})
+}
+
+// Verify a phone number
+//
+//	@Summary		Verify phone number
+//	@Description	Confirms the number added with /auth/phone using the code sent to it. The number can then be used to sign in.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		phoneVerifyData			true	"Code sent by SMS"
+//	@Success		200		{object}	utils.GenericResponse	"Phone number verified"
+//	@Failure		400		{object}	utils.CommonError		"Invalid or expired code"
+//	@Failure		409		{object}	utils.CommonError		"Phone number is used by another account"
+//	@Router			/auth/phone/verify [post]
+func (am *AuthModule) verifyPhone(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(phoneVerifyData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	phone, ok, err := am.redeemPhoneOTP(ctx, principal.ID, req.OTP)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		am.logFailure(ctx, c, eventPhoneVerify, principal.ID, "invalid_code")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid or expired code")
+	}
+
+	if err := am.user.SetVerifiedPhone(ctx, userServices.SetVerifiedPhoneParams{
+		Phone: sql.NullString{String: phone, Valid: true},
+		ID:    principal.ID,
+	}); err != nil {
+		if isUniqueViolation(err) {
+			return fiber.NewError(fiber.StatusConflict, "Phone number is used by another account")
+		}
+		return err
+	}
+	am.logEvent(ctx, c, eventPhoneVerify, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"phone":   phone,
+			"message": "Phone number verified successfully",
+		},
+	})
+}
+
+// Send a phone login code
+//
+//	@Summary		Send phone login code
+//	@Description	Texts a 6-digit sign-in code to a verified phone number. The response is the same whether or not the number belongs to an account.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		phoneData				true	"Phone number"
+//	@Success		200		{object}	utils.GenericResponse	"Code sent if the number is registered"
+//	@Failure		400		{object}	utils.CommonError		"Invalid phone number"
+//	@Failure		429		{object}	utils.CommonError		"Too many codes requested"
+//	@Router			/auth/phone/login/send [post]
+func (am *AuthModule) sendPhoneLoginCode(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(phoneData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	phone, err := utils.NormalizePhone(req.Phone)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid phone number")
+	}
+
+	if err := am.throttlePhoneOTP(ctx, c, phone); err != nil {
+		return err
+	}
+
+	user, err := am.user.GetUserByPhone(ctx, sql.NullString{String: phone, Valid: true})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && user.IsActive {
+		if err := am.sendPhoneOTP(ctx, user.ID, phone); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "If the number is registered, a sign-in code has been sent",
+		},
+	})
+}
+
+// Login with phone
+//
+//	@Summary		Login with phone
+//	@Description	Signs in with a verified phone number and the code from /auth/phone/login/send. Responds like /auth/login, including the two-factor step when it is enabled.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		phoneLoginData			true	"Phone number and code"
+//	@Success		200		{object}	utils.GenericResponse	"Login successful. Contains user info and access token."
+//	@Failure		401		{object}	utils.CommonError		"Invalid phone number or code"
+//	@Failure		423		{object}	utils.CommonError		"Locked: Too many failed attempts"
+//	@Router			/auth/phone/login [post]
+func (am *AuthModule) phoneLogin(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	req := new(phoneLoginData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := am.validate.Struct(req); err != nil {
+		return err
+	}
+
+	phone, err := utils.NormalizePhone(req.Phone)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid phone number or code")
+	}
+
+	user, err := am.user.GetUserByPhone(ctx, sql.NullString{String: phone, Valid: true})
+	if err != nil {
+		am.logFailure(ctx, c, eventLogin, uuid.Nil, "unknown_phone")
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid phone number or code")
+	}
+	if !user.IsActive {
+		am.logFailure(ctx, c, eventLogin, user.ID, "deactivated")
+		return fiber.NewError(fiber.StatusUnauthorized, "User account is deactivated. Contact support.")
+	}
+	if err := accountLocked(c, user.LockedUntil); err != nil {
+		am.logFailure(ctx, c, eventLogin, user.ID, "locked")
+		return err
+	}
+
+	sentTo, ok, err := am.redeemPhoneOTP(ctx, user.ID, req.OTP)
+	if err != nil {
+		return err
+	}
+	if !ok || sentTo != phone {
+		am.logFailure(ctx, c, eventLogin, user.ID, "invalid_code")
+		if err := am.recordFailedLogin(ctx, c, user.ID); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid phone number or code")
+	}
+
+	// Reset failed login attempts
+	if err := am.user.ResetFailedLogin(ctx, user.ID); err != nil {
+		return err
+	}
+
+	// Issue tokens, or ask for the second factor
+	return am.completeLogin(ctx, c, user.ID, "phone")
+}
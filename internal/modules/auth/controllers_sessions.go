@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// List active sessions
+//
+//	@Summary		List active sessions
+//	@Description	Lists the devices the current user is signed in on, with user agent, IP address, sign-in and last-used times. The session of the current access token is flagged with current=true.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Active sessions"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/auth/sessions [get]
+func (am *AuthModule) listSessions(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := am.queries.ListActiveSessions(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+
+	data := make([]fiber.Map, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, fiber.Map{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IpAddress,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == principal.SessionID,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// Revoke a session
+//
+//	@Summary		Revoke a session
+//	@Description	Signs the current user out of one of their sessions. Refresh and access tokens issued for it stop working immediately.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Param			id	path		string					true	"Session ID"
+//	@Success		200	{object}	utils.GenericResponse	"Session revoked"
+//	@Failure		400	{object}	utils.CommonError		"Bad Request: Invalid session ID"
+//	@Failure		404	{object}	utils.CommonError		"Session not found"
+//	@Router			/auth/sessions/{id} [delete]
+func (am *AuthModule) revokeSession(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	revoked, err := am.queries.RevokeUserSessionFamily(ctx, authServices.RevokeUserSessionFamilyParams{
+		FamilyID: sessionID,
+		UserID:   principal.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "Session not found")
+	}
+	am.logEvent(ctx, c, eventSessionRevoke, principal.ID, "")
+
+	// Signing out of the current device also clears its refresh cookie
+	if sessionID == principal.SessionID {
+		am.jwt.GetExpiredRefreshCookie(c)
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Session revoked successfully",
+		},
+	})
+}
+
+// Sign out of all other sessions
+//
+//	@Summary		Sign out everywhere else
+//	@Description	Revokes every session of the current user except the one the access token belongs to.
+//	@Tags			Auth
+//	@Produce		json
+//	@Security		JWT
+//	@Success		200	{object}	utils.GenericResponse	"Number of sessions revoked"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/auth/logout-all [post]
+func (am *AuthModule) logoutAll(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := am.queries.RevokeOtherUserSessions(ctx, authServices.RevokeOtherUserSessionsParams{
+		UserID:   principal.ID,
+		FamilyID: principal.SessionID,
+	})
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventLogoutAll, principal.ID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Signed out of all other sessions",
+			"revoked": revoked,
+		},
+	})
+}
+
+// Force-logout a user
+//
+//	@Summary		Force-logout user
+//	@Description	Revokes every session of the given user, signing them out on all devices. Requires the sessions:revoke permission.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			id	path		string					true	"User ID"
+//	@Success		200	{object}	utils.GenericResponse	"Number of sessions revoked"
+//	@Failure		403	{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Failure		404	{object}	utils.CommonError		"User not found"
+//	@Router			/admin/users/{id}/sessions [delete]
+func (am *AuthModule) forceLogout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if _, err := am.user.GetUserById(ctx, userID); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	revoked, err := am.queries.RevokeAllUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventForceLogout, userID, "")
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "User signed out of all sessions",
+			"revoked": revoked,
+		},
+	})
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+// Events recorded in the auth_events audit log.
+const (
+	eventRegister             = "register"
+	eventLogin                = "login"
+	eventLogout               = "logout"
+	eventLoginChallenge       = "login_challenge"
+	eventLoginAlert           = "login_alert"
+	eventLoginReport          = "login_report"
+	eventLockout              = "lockout"
+	eventUnlock               = "unlock"
+	eventRefreshTokenReuse    = "refresh_token_reuse"
+	eventMagicLinkRequest     = "magic_link_request"
+	eventPasswordResetRequest = "password_reset_request"
+	eventPasswordReset        = "password_reset"
+	eventPasswordChange       = "password_change"
+	eventEmailVerify          = "email_verify"
+	eventEmailChangeRequest   = "email_change_request"
+	eventEmailChange          = "email_change"
+	eventEmailChangeUndo      = "email_change_undo"
+	eventMFAEnable            = "mfa_enable"
+	eventMFADisable           = "mfa_disable"
+	eventRecoveryCodes        = "recovery_codes_regenerate"
+	eventPasskeyAdd           = "passkey_add"
+	eventPasskeyRemove        = "passkey_remove"
+	eventPhoneVerify          = "phone_verify"
+	eventIdentityLink         = "identity_link"
+	eventIdentityUnlink       = "identity_unlink"
+	eventSessionRevoke        = "session_revoke"
+	eventLogoutAll            = "logout_all"
+	eventForceLogout          = "force_logout"
+	eventImpersonationStart   = "impersonation_start"
+	eventImpersonationStop    = "impersonation_stop"
+	eventAPIKeyCreate         = "api_key_create"
+	eventAPIKeyRevoke         = "api_key_revoke"
+	eventAccountRestore       = "account_restore"
+	eventInvitationCreate     = "invitation_create"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+// logEvent records that event succeeded for the user. detail says how, such
+// as the sign-in method, and may be empty.
+func (am *AuthModule) logEvent(ctx context.Context, c *fiber.Ctx, event string, userID uuid.UUID, detail string) {
+	am.recordEvent(ctx, c, event, outcomeSuccess, userID, detail)
+}
+
+// logFailure records that event failed for reason. userID is uuid.Nil when no
+// account matched, e.g. a login with an unknown email.
+func (am *AuthModule) logFailure(ctx context.Context, c *fiber.Ctx, event string, userID uuid.UUID, reason string) {
+	am.recordEvent(ctx, c, event, outcomeFailure, userID, reason)
+}
+
+// recordEvent appends an event to the audit log. The signed-in user making
+// the request, if any, is its actor, or the staff member impersonating them.
+// A failed write is logged rather than failing the request.
+func (am *AuthModule) recordEvent(ctx context.Context, c *fiber.Ctx, event, outcome string, userID uuid.UUID, reason string) {
+	var actorID uuid.NullUUID
+	if principal, err := middlewares.GetPrincipal(c); err == nil {
+		actorID = uuid.NullUUID{UUID: principal.ID, Valid: true}
+		if principal.IsImpersonated() {
+			actorID = principal.ImpersonatorID
+		}
+	}
+
+	if err := am.queries.CreateAuthEvent(ctx, authServices.CreateAuthEventParams{
+		Event:     event,
+		Outcome:   outcome,
+		Reason:    reason,
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		ActorID:   actorID,
+		IpAddress: c.IP(),
+		UserAgent: utils.Truncate(c.Get(fiber.HeaderUserAgent), 512),
+	}); err != nil {
+		log.Errorf("failed to record %s %s event for user %s: %v", event, outcome, userID, err)
+	}
+}
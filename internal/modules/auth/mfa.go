@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaPendingTokenType = "mfa_pending"
+	mfaPendingExpiry    = 5 * time.Minute
+
+	totpIssuer = "Varaden"
+	// Accept codes from one period before and after the current one
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+// completeLogin finishes a login once the first factor has been checked. Users
+// with two-factor authentication get a short-lived mfa_pending token to redeem
+// at /auth/mfa/verify; everyone else gets a session right away. method is the
+// sign-in method recorded in the audit log.
+func (am *AuthModule) completeLogin(ctx context.Context, c *fiber.Ctx, userID uuid.UUID, method string) error {
+	mfa, err := am.queries.GetUserMFA(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err == nil && mfa.EnabledAt.Valid {
+		mfaToken, err := am.jwt.GenerateFlowToken(mfaPendingTokenType, map[string]any{
+			"sub": userID.String(),
+		}, mfaPendingExpiry)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"data": fiber.Map{
+				"id":           userID,
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+			},
+		})
+	}
+
+	return am.loginResponse(ctx, c, userID, method)
+}
+
+// loginResponse starts a session and responds with the user info and access
+// token, as /auth/login does, and records the login. Risky sign-ins get a
+// short-lived login_step_up token and an emailed code to redeem at
+// /auth/login/verify instead when step-up is enabled.
+func (am *AuthModule) loginResponse(ctx context.Context, c *fiber.Ctx, userID uuid.UUID, method string) error {
+	user, err := am.user.GetUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	risk, err := am.assessLogin(ctx, c, user.ID)
+	if err != nil {
+		return err
+	}
+	if am.requiresStepUp(risk, method) {
+		return am.startStepUp(ctx, c, user.ID, user.Email, method, risk)
+	}
+
+	return am.finishLogin(ctx, c, user, method, risk)
+}
+
+// finishLogin starts a session for a sign-in that passed every check, records
+// its device and alerts the user if it is one they have not used before.
+func (am *AuthModule) finishLogin(ctx context.Context, c *fiber.Ctx, user userServices.GetUserByIdRow, method string, risk loginRisk) error {
+	// Start a session and issue JWT tokens
+	tokens, err := am.startSession(ctx, c, user.ID)
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventLogin, user.ID, method)
+	am.trackDevice(ctx, c, user.ID, user.Email, risk)
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"email":          user.Email,
+			"name":           user.Name,
+			"id":             user.ID,
+			"verified_email": user.VerifiedEmail,
+			"aToken":         tokens.Token,
+		},
+	})
+}
+
+// verifyTOTP checks a code against the user's TOTP secret and consumes its time
+// step, so the same code cannot be used twice.
+func (am *AuthModule) verifyTOTP(ctx context.Context, mfa authServices.UserMfa, code string) (bool, error) {
+	secret, err := utils.Decrypt(config.EncryptionKey, mfa.TotpSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	used, err := am.queries.UseTOTPStep(ctx, authServices.UseTOTPStepParams{
+		UserID:       mfa.UserID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return used == 1, nil
+}
+
+// useRecoveryCode redeems one of the user's unused recovery codes.
+func (am *AuthModule) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	used, err := am.queries.UseRecoveryCode(ctx, authServices.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashRecoveryCode(code),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return used == 1, nil
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and stores hashes of
+// a fresh set. The plaintext codes are returned to be shown once.
+func (am *AuthModule) replaceRecoveryCodes(ctx context.Context, queries *authServices.Queries, userID uuid.UUID) ([]string, error) {
+	if err := queries.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = generateRecoveryCode()
+		if err := queries.CreateRecoveryCode(ctx, authServices.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(codes[i]),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx.
+func generateRecoveryCode() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case and separators.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE sessions (
+    -- Refresh token ID (jti claim)
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- Every refresh token rotated from the same login shares a family (sid claim)
+    family_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    -- When the family was created by a login, carried over on rotation
+    authenticated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Set once the token has been exchanged for a new one
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Indexes for performance
+CREATE INDEX sessions_family_id ON sessions (family_id);
+CREATE INDEX sessions_user_id ON sessions (user_id);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
\ No newline at end of file
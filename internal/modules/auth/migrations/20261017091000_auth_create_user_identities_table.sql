@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- External identity provider, e.g. google
+    provider VARCHAR(50) NOT NULL,
+    -- Stable user ID at the provider (sub claim)
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    linked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- A provider account belongs to one user, and a user links one account per provider
+    CONSTRAINT unique_identity_provider_subject UNIQUE (provider, subject),
+    CONSTRAINT unique_identity_user_provider UNIQUE (user_id, provider)
+);
+-- Indexes for performance
+CREATE INDEX user_identities_user_id ON user_identities (user_id);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_identities;
+-- +goose StatementEnd
\ No newline at end of file
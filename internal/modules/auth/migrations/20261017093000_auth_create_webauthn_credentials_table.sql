@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webauthn_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id BYTEA NOT NULL UNIQUE,
+    -- COSE encoded credential public key
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR(32) NOT NULL DEFAULT '',
+    -- Comma separated authenticator transports, e.g. internal,hybrid
+    transports VARCHAR(255) NOT NULL DEFAULT '',
+    aaguid BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    -- Raw authenticator data flags (UP, UV, BE, BS)
+    flags SMALLINT NOT NULL DEFAULT 0,
+    -- Label chosen by the user, e.g. "MacBook"
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Indexes for performance
+CREATE INDEX webauthn_credentials_user_id ON webauthn_credentials (user_id);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webauthn_credentials;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The phone number or email address a token was sent to, when it is not the
+-- user's current one (e.g. a phone number being added)
+ALTER TABLE tokens
+ADD COLUMN target VARCHAR(255);
+-- Every SMS code sent, for per-number throttling
+CREATE TABLE phone_otp_sends (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    phone VARCHAR(20) NOT NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Indexes for performance
+CREATE INDEX phone_otp_sends_phone_sent_at ON phone_otp_sends (phone, sent_at);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS phone_otp_sends;
+ALTER TABLE tokens DROP COLUMN IF EXISTS target;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- Public start of the key (vrd_xxxxxxxx), used to look it up and shown in lists
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    -- SHA-256 of the full key; the key itself is only shown once
+    key_hash VARCHAR(64) NOT NULL,
+    -- Comma separated permissions the key may use, e.g. users:read
+    scopes VARCHAR(1024) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Indexes for performance
+CREATE INDEX api_keys_user_id ON api_keys (user_id);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Request counters of the Postgres rate limit store, one row per key and
+-- fixed window
+CREATE TABLE rate_limit_hits (
+    key VARCHAR(128) NOT NULL,
+    -- Start of the window in Unix seconds
+    window_start BIGINT NOT NULL,
+    hits BIGINT NOT NULL DEFAULT 1,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (key, window_start)
+);
+-- Indexes for performance
+CREATE INDEX rate_limit_hits_expires_at ON rate_limit_hits (expires_at);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limit_hits;
+-- +goose StatementEnd
\ No newline at end of file
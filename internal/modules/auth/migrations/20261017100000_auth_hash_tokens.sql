@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tokens are stored as keyed hashes in token_hash. Existing plaintext tokens
+-- keep working once hashed with `cli tokens:hash`, which also clears them.
+ALTER TABLE tokens
+ALTER COLUMN token DROP NOT NULL,
+    ADD COLUMN token_hash VARCHAR(64),
+    -- Wrong guesses at a one-time code; the code is voided after too many
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX tokens_token_hash ON tokens (token_hash);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+-- Hashed tokens cannot be turned back into plaintext
+DELETE FROM tokens
+WHERE token IS NULL;
+DROP INDEX IF EXISTS tokens_token_hash;
+ALTER TABLE tokens DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS token_hash,
+    ALTER COLUMN token
+SET NOT NULL;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE auth_events (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- What happened, e.g. login or password_reset
+    event VARCHAR(50) NOT NULL,
+    outcome VARCHAR(10) NOT NULL CHECK (outcome IN ('success', 'failure')),
+    -- Why it failed, or how it succeeded, e.g. invalid_password or passkey
+    reason VARCHAR(100) NOT NULL DEFAULT '',
+    -- The account the event is about. No foreign key, so events outlive the
+    -- account they describe.
+    user_id UUID,
+    -- The signed-in user who made the request, e.g. an admin; NULL for
+    -- requests made before signing in
+    actor_id UUID,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Indexes for performance
+CREATE INDEX auth_events_user_id ON auth_events (user_id, id);
+CREATE INDEX auth_events_created_at ON auth_events (created_at);
+-- Events are append-only; only the retention purge deletes them
+CREATE OR REPLACE FUNCTION reject_auth_event_update() RETURNS TRIGGER AS $$ BEGIN
+RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE 'plpgsql';
+CREATE TRIGGER auth_events_append_only BEFORE
+UPDATE ON auth_events FOR EACH ROW EXECUTE FUNCTION reject_auth_event_update();
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS auth_events;
+DROP FUNCTION IF EXISTS reject_auth_event_update();
+-- +goose StatementEnd
\ No newline at end of file
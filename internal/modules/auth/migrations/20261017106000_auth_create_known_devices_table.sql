@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE known_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Hash of the user agent without version numbers, so updates keep the device
+    device_hash VARCHAR(64) NOT NULL,
+    -- The /24 (IPv4) or /48 (IPv6) network the login came from
+    network VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    -- Approximate location from the GeoIP database, empty when unknown
+    country_code VARCHAR(2) NOT NULL DEFAULT '',
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    accuracy_km INT NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, device_hash, network)
+);
+CREATE INDEX known_devices_user_id_last_seen_at ON known_devices (user_id, last_seen_at);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS known_devices;
+-- +goose StatementEnd
\ No newline at end of file
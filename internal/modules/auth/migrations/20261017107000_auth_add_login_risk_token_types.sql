@@ -0,0 +1,16 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE token_type
+ADD VALUE IF NOT EXISTS 'login_step_up';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TYPE token_type
+ADD VALUE IF NOT EXISTS 'login_alert';
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+-- Enum values cannot be dropped; remove the tokens that use them instead
+DELETE FROM tokens
+WHERE type IN ('login_step_up', 'login_alert');
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE token_type
+ADD VALUE IF NOT EXISTS 'account_restore';
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+-- Enum values cannot be dropped; remove the tokens that use it instead
+DELETE FROM tokens
+WHERE type = 'account_restore';
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- What the invitee enters when registering
+    code VARCHAR(32) NOT NULL UNIQUE,
+    inviter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Only this address may register with the code, if set
+    email VARCHAR(250),
+    max_uses INT NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0 CHECK (uses <= max_uses),
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Who registered with which invitation, and so who invited whom
+CREATE TABLE invitation_redemptions (
+    invitation_id UUID NOT NULL REFERENCES invitations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    redeemed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (invitation_id, user_id)
+);
+-- Indexes for performance
+CREATE INDEX invitations_inviter_id ON invitations (inviter_id, created_at);
+CREATE INDEX invitations_created_at ON invitations (created_at);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS invitation_redemptions;
+DROP TABLE IF EXISTS invitations;
+-- +goose StatementEnd
\ No newline at end of file
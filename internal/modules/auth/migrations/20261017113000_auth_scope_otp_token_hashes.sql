@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Two users can hold the same one-time code, so only link tokens, which are
+-- found by their hash alone, need a unique hash
+DROP INDEX IF EXISTS tokens_token_hash;
+CREATE INDEX tokens_token_hash ON tokens (token_hash);
+CREATE UNIQUE INDEX tokens_link_token_hash ON tokens (token_hash)
+WHERE type NOT IN ('email_verify', 'phone_verify', 'login_step_up');
+-- One-time codes are now hashed with their user and type, so outstanding
+-- ones no longer match; users request a new code
+DELETE FROM tokens
+WHERE type IN ('email_verify', 'phone_verify', 'login_step_up')
+    AND token_hash IS NOT NULL;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM tokens
+WHERE type IN ('email_verify', 'phone_verify', 'login_step_up')
+    AND token_hash IS NOT NULL;
+DROP INDEX IF EXISTS tokens_link_token_hash;
+DROP INDEX IF EXISTS tokens_token_hash;
+CREATE UNIQUE INDEX tokens_token_hash ON tokens (token_hash);
+-- +goose StatementEnd
\ No newline at end of file
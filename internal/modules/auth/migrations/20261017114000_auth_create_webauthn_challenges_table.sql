@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Session data of passkey ceremonies between begin and finish. Finishing
+-- deletes it, so each challenge can be answered once.
+CREATE TABLE webauthn_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- passkey_register or passkey_login
+    type VARCHAR(32) NOT NULL,
+    -- webauthn.SessionData as JSON
+    session JSONB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Indexes for performance
+CREATE INDEX webauthn_challenges_expires_at ON webauthn_challenges (expires_at);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webauthn_challenges;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Events stay append-only, except that anonymizing a deleted account may
+-- blank the IP address and user agent of its events
+CREATE OR REPLACE FUNCTION reject_auth_event_update() RETURNS TRIGGER AS $$ BEGIN
+IF NEW.ip_address = ''
+AND NEW.user_agent = ''
+AND (
+    NEW.id,
+    NEW.event,
+    NEW.outcome,
+    NEW.reason,
+    NEW.user_id,
+    NEW.actor_id,
+    NEW.created_at
+) IS NOT DISTINCT
+FROM (
+        OLD.id,
+        OLD.event,
+        OLD.outcome,
+        OLD.reason,
+        OLD.user_id,
+        OLD.actor_id,
+        OLD.created_at
+    ) THEN RETURN NEW;
+END IF;
+RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE 'plpgsql';
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reject_auth_event_update() RETURNS TRIGGER AS $$ BEGIN
+RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE 'plpgsql';
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/oauth2"
+)
+
+const (
+	googleProvider = "google"
+
+	oauthStateCookieName = "__oauth_state"
+	oauthStateTokenType  = "oauth_state"
+	oauthStateExpiry     = 10 * time.Minute
+)
+
+// oidcClient talks to an OpenID Connect provider. The provider is discovered on
+// first use so the API can start while the identity provider is unreachable.
+type oidcClient struct {
+	cfg config.OAuthConfig
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func newOIDCClient(cfg config.OAuthConfig) *oidcClient {
+	return &oidcClient{cfg: cfg}
+}
+
+func (oc *oidcClient) load(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	if oc.cfg.ClientID == "" {
+		return nil, nil, fiber.NewError(fiber.StatusServiceUnavailable, "Google login is not configured")
+	}
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if oc.oauth2 != nil {
+		return oc.oauth2, oc.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, oc.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover OpenID provider %s: %w", oc.cfg.Issuer, err)
+	}
+
+	oc.oauth2 = &oauth2.Config{
+		ClientID:     oc.cfg.ClientID,
+		ClientSecret: oc.cfg.ClientSecret,
+		RedirectURL:  oc.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+	oc.verifier = provider.Verifier(&oidc.Config{ClientID: oc.cfg.ClientID})
+
+	return oc.oauth2, oc.verifier, nil
+}
+
+// oidcIdentity is the verified ID token of a provider account.
+type oidcIdentity struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// beginOAuthFlow keeps state, nonce and the PKCE verifier in the flow cookie and
+// returns the provider URL to redirect to. A non-nil linkUserID makes the
+// callback link the provider account to that user instead of signing in.
+func (am *AuthModule) beginOAuthFlow(ctx context.Context, c *fiber.Ctx, linkUserID uuid.UUID) (string, error) {
+	oauthConfig, _, err := am.google.load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state := utils.GenerateRandomString(32)
+	nonce := utils.GenerateRandomString(32)
+	verifier := oauth2.GenerateVerifier()
+
+	flow := map[string]any{
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+	}
+	if linkUserID != uuid.Nil {
+		flow["link_user_id"] = linkUserID.String()
+	}
+
+	stateToken, err := am.jwt.GenerateFlowToken(oauthStateTokenType, flow, oauthStateExpiry)
+	if err != nil {
+		return "", err
+	}
+	am.setOAuthStateCookie(c, stateToken)
+
+	return oauthConfig.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("prompt", "select_account"),
+	), nil
+}
+
+// completeOAuthFlow checks the callback state against the flow cookie,
+// exchanges the code and verifies the ID token signature, audience, expiry and
+// nonce. It returns the verified identity and the user to link it to, if any.
+func (am *AuthModule) completeOAuthFlow(ctx context.Context, c *fiber.Ctx) (oidcIdentity, uuid.UUID, error) {
+	oauthConfig, verifier, err := am.google.load(ctx)
+	if err != nil {
+		return oidcIdentity{}, uuid.Nil, err
+	}
+
+	// Validate state against the flow cookie
+	flow, err := am.jwt.FlowTokenValidate(c.Cookies(oauthStateCookieName), oauthStateTokenType)
+	am.clearOAuthStateCookie(c)
+	if err != nil {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Login session expired. Start again.")
+	}
+	state, _ := flow["state"].(string)
+	nonce, _ := flow["nonce"].(string)
+	pkceVerifier, _ := flow["verifier"].(string)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid state")
+	}
+	if c.Query("error") != "" {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Google login was cancelled or denied")
+	}
+
+	linkUserID := uuid.Nil
+	if link, ok := flow["link_user_id"].(string); ok {
+		if linkUserID, err = uuid.Parse(link); err != nil {
+			return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid state")
+		}
+	}
+
+	// Exchange the code and verify the ID token
+	oauthToken, err := oauthConfig.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(pkceVerifier))
+	if err != nil {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid authorization code")
+	}
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Missing ID token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid ID token")
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid nonce")
+	}
+
+	var identity oidcIdentity
+	if err := idToken.Claims(&identity); err != nil {
+		return oidcIdentity{}, uuid.Nil, err
+	}
+	if identity.Email == "" {
+		return oidcIdentity{}, uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Google account has no email address")
+	}
+
+	return identity, linkUserID, nil
+}
+
+// resolveOAuthUser returns the user a provider identity signs in as. Unknown
+// identities are linked to the account with the same email, or get a new
+// account, but only when the provider has verified the email address.
+func (am *AuthModule) resolveOAuthUser(ctx context.Context, provider string, identity oidcIdentity) (userServices.GetUserByIdRow, error) {
+	linked, err := am.queries.GetIdentity(ctx, authServices.GetIdentityParams{
+		Provider: provider,
+		Subject:  identity.Subject,
+	})
+	if err == nil {
+		return am.user.GetUserById(ctx, linked.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return userServices.GetUserByIdRow{}, err
+	}
+
+	existing, err := am.user.GetUserByEmail(ctx, identity.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return userServices.GetUserByIdRow{}, err
+	}
+	found := err == nil
+
+	if !identity.EmailVerified {
+		if found {
+			return userServices.GetUserByIdRow{}, fiber.NewError(fiber.StatusConflict, "An account with this email already exists. Sign in with your password.")
+		}
+		return userServices.GetUserByIdRow{}, fiber.NewError(fiber.StatusForbidden, "Google has not verified this email address")
+	}
+	// Google sign-in has nowhere to enter an invitation code
+	if !found && am.signup.Mode == config.RegistrationInvite {
+		return userServices.GetUserByIdRow{}, fiber.NewError(fiber.StatusForbidden, "Registration is by invitation only. Register with your invitation code first.")
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return userServices.GetUserByIdRow{}, err
+	}
+	defer tx.Rollback()
+	userTx := am.user.WithTx(tx)
+	qtx := am.queries.WithTx(tx)
+
+	userID := existing.ID
+	if !found {
+		newUser, err := userTx.CreateOAuthUser(ctx, userServices.CreateOAuthUserParams{
+			Email:         identity.Email,
+			Name:          utils.Truncate(identity.Name, 255),
+			VerifiedEmail: true,
+		})
+		if err != nil {
+			return userServices.GetUserByIdRow{}, utils.DuplicateEntryError(err, "email")
+		}
+		userID = newUser.ID
+	} else if !existing.VerifiedEmail {
+		// Nobody proved they own the mailbox when this account was registered, so
+		// it may have been created by someone else to hijack the Google sign-in.
+		// Drop its password and sessions before handing it to the mailbox owner.
+		if err := userTx.UpdatePassword(ctx, userServices.UpdatePasswordParams{
+			PasswordHash: "",
+			ID:           existing.ID,
+		}); err != nil {
+			return userServices.GetUserByIdRow{}, err
+		}
+		if err := userTx.VerifyUserEmail(ctx, existing.ID); err != nil {
+			return userServices.GetUserByIdRow{}, err
+		}
+		if _, err := qtx.RevokeAllUserSessions(ctx, existing.ID); err != nil {
+			return userServices.GetUserByIdRow{}, err
+		}
+	}
+
+	if _, err := qtx.CreateIdentity(ctx, authServices.CreateIdentityParams{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		if isUniqueViolation(err) {
+			return userServices.GetUserByIdRow{}, fiber.NewError(fiber.StatusConflict, "This account is already linked to a different Google account")
+		}
+		return userServices.GetUserByIdRow{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return userServices.GetUserByIdRow{}, err
+	}
+
+	return am.user.GetUserById(ctx, userID)
+}
+
+// linkIdentity links a provider identity to a signed-in user.
+func (am *AuthModule) linkIdentity(ctx context.Context, userID uuid.UUID, provider string, identity oidcIdentity) error {
+	linked, err := am.queries.GetIdentity(ctx, authServices.GetIdentityParams{
+		Provider: provider,
+		Subject:  identity.Subject,
+	})
+	if err == nil {
+		if linked.UserID == userID {
+			return nil
+		}
+		return fiber.NewError(fiber.StatusConflict, "This Google account is linked to another user")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = am.queries.CreateIdentity(ctx, authServices.CreateIdentityParams{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if isUniqueViolation(err) {
+		return fiber.NewError(fiber.StatusConflict, "A Google account is already linked. Unlink it first.")
+	}
+	return err
+}
+
+// loginMethodCount returns how many ways the user has to sign in: a password,
+// each linked identity and each passkey. It locks the user's row until tx
+// ends, so concurrent removals cannot both see a second method left.
+func (am *AuthModule) loginMethodCount(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (int64, error) {
+	passwordHash, err := am.user.WithTx(tx).GetUserPasswordHashForUpdate(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	qtx := am.queries.WithTx(tx)
+	count, err := qtx.CountUserIdentities(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	passkeys, err := qtx.CountUserWebauthnCredentials(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	count += passkeys
+	if passwordHash != "" {
+		count++
+	}
+
+	return count, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
+}
+
+func (am *AuthModule) setOAuthStateCookie(c *fiber.Ctx, value string) {
+	cookie := new(fiber.Cookie)
+	cookie.Name = oauthStateCookieName
+	cookie.Path = "/"
+	cookie.Value = value
+	cookie.MaxAge = int(oauthStateExpiry.Seconds())
+	// Lax so the cookie is sent on the provider's top-level redirect back to us
+	cookie.SameSite = fiber.CookieSameSiteLaxMode
+	cookie.Secure = true
+	cookie.HTTPOnly = true
+
+	c.Cookie(cookie)
+}
+
+func (am *AuthModule) clearOAuthStateCookie(c *fiber.Ctx) {
+	cookie := new(fiber.Cookie)
+	cookie.Name = oauthStateCookieName
+	cookie.Path = "/"
+	cookie.Value = ""
+	cookie.Expires = time.Unix(0, 0)
+	cookie.MaxAge = -1
+	cookie.SameSite = fiber.CookieSameSiteLaxMode
+	cookie.Secure = true
+	cookie.HTTPOnly = true
+
+	c.Cookie(cookie)
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID   = "test-client"
+	testSigningKID = "test-key"
+)
+
+// testOIDCProvider is an OpenID provider that hands out an ID token with the
+// claims set by the test for any authorization code.
+type testOIDCProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims jwt.MapClaims
+}
+
+func newTestOIDCProvider(t *testing.T) *testOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{
+			"issuer":                                p.URL,
+			"authorization_endpoint":                p.URL + "/authorize",
+			"token_endpoint":                        p.URL + "/token",
+			"jwks_uri":                              p.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": testSigningKID,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", p.token)
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *testOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("code") == "" || r.PostForm.Get("code_verifier") == "" {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, p.claims)
+	p.mu.Unlock()
+	idToken.Header["kid"] = testSigningKID
+
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeTestJSON(w, map[string]any{
+		"access_token": "test-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// login runs the Google sign-in flow as a browser would: the provider signs
+// the user in as the account described by email and verified.
+func (p *testOIDCProvider) login(t *testing.T, app *fiber.App, subject, email string, verified bool) (*http.Response, string) {
+	t.Helper()
+
+	resp, body := doRequest(t, app, httptest.NewRequest(http.MethodGet, "/api/v1/auth/google", nil))
+	if resp.StatusCode != fiber.StatusTemporaryRedirect {
+		t.Fatalf("GET /auth/google = %d %s, want a redirect", resp.StatusCode, body)
+	}
+	authURL, err := url.Parse(resp.Header.Get(fiber.HeaderLocation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stateCookie *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oauthStateCookieName {
+			stateCookie = cookie
+		}
+	}
+	if stateCookie == nil {
+		t.Fatal("GET /auth/google did not set the state cookie")
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.claims = jwt.MapClaims{
+		"iss":            p.URL,
+		"aud":            testClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": verified,
+		"name":           "Test User",
+		"nonce":          authURL.Query().Get("nonce"),
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	p.mu.Unlock()
+
+	callback := url.Values{
+		"code":  {"test-code"},
+		"state": {authURL.Query().Get("state")},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/google-callback?"+callback.Encode(), nil)
+	req.AddCookie(stateCookie)
+	return doRequest(t, app, req)
+}
+
+func (p *testOIDCProvider) config() config.OAuthConfig {
+	return config.OAuthConfig{
+		ClientID:     testClientID,
+		ClientSecret: "test-secret",
+		RedirectURL:  "https://api.varaden.test/api/v1/auth/google-callback",
+		Issuer:       p.URL,
+	}
+}
+
+func writeTestJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestGoogleLoginCreatesUser(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	app, am := newTestApp(t, provider.config())
+	ctx := context.Background()
+
+	resp, body := provider.login(t, app, "google-new", "new@example.test", true)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("callback = %d %s, want 200", resp.StatusCode, body)
+	}
+
+	user, err := am.user.GetUserByEmail(ctx, "new@example.test")
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+	if !user.VerifiedEmail {
+		t.Error("new user's email is not marked verified")
+	}
+	if user.PasswordHash != "" {
+		t.Error("new user has a password")
+	}
+
+	identity, err := am.queries.GetIdentity(ctx, authServices.GetIdentityParams{
+		Provider: googleProvider,
+		Subject:  "google-new",
+	})
+	if err != nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+	if identity.UserID != user.ID {
+		t.Errorf("identity linked to %s, want %s", identity.UserID, user.ID)
+	}
+}
+
+func TestGoogleLoginLinksVerifiedEmail(t *testing.T) {
+	provider := newTestOIDCProvider(t)
+	app, am := newTestApp(t, provider.config())
+	ctx := context.Background()
+	existingID := createUser(t, am, "existing@example.test", true)
+
+	resp, body := provider.login(t, app, "google-existing", "existing@example.test", true)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("callback = %d %s, want 200", resp.StatusCode, body)
+	}
+
+	identity, err := am.queries.GetIdentity(ctx, authServices.GetIdentityParams{
+		Provider: googleProvider,
+		Subject:  "google-existing",
+	})
+	if err != nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+	if identity.UserID != existingID {
+		t.Errorf("identity linked to %s, want the existing user %s", identity.UserID, existingID)
+	}
+
+	// Signing in again finds the user by the linked identity
+	resp, body = provider.login(t, app, "google-existing", "existing@example.test", true)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("second callback = %d %s, want 200", resp.StatusCode, body)
+	}
+}
+
+func TestGoogleLoginRejectsUnverifiedEmail(t *testing.T) {
+	t.Run("new account", func(t *testing.T) {
+		provider := newTestOIDCProvider(t)
+		app, am := newTestApp(t, provider.config())
+
+		resp, body := provider.login(t, app, "google-unverified", "unverified@example.test", false)
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Fatalf("callback = %d %s, want 403", resp.StatusCode, body)
+		}
+
+		if _, err := am.user.GetUserByEmail(context.Background(), "unverified@example.test"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetUserByEmail err = %v, want no user created", err)
+		}
+	})
+
+	t.Run("existing account", func(t *testing.T) {
+		provider := newTestOIDCProvider(t)
+		app, am := newTestApp(t, provider.config())
+		createUser(t, am, "taken@example.test", true)
+
+		resp, body := provider.login(t, app, "google-unverified", "taken@example.test", false)
+		if resp.StatusCode != fiber.StatusConflict {
+			t.Fatalf("callback = %d %s, want 409", resp.StatusCode, body)
+		}
+
+		_, err := am.queries.GetIdentity(context.Background(), authServices.GetIdentityParams{
+			Provider: googleProvider,
+			Subject:  "google-unverified",
+		})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetIdentity err = %v, want the identity left unlinked", err)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	passkeyRegisterTokenType = "passkey_register"
+	passkeyLoginTokenType    = "passkey_login"
+	passkeyCeremonyExpiry    = 5 * time.Minute
+
+	passkeyDisplayName = "Varaden"
+)
+
+// newWebAuthn configures the relying party from the front end URL: its host is
+// the RP ID and its origin the only origin allowed to run ceremonies.
+func newWebAuthn(frontEndURL string) (*webauthn.WebAuthn, error) {
+	origin, err := url.Parse(frontEndURL)
+	if err != nil {
+		return nil, err
+	}
+	if origin.Hostname() == "" {
+		return nil, fmt.Errorf("invalid front end URL %q", frontEndURL)
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          origin.Hostname(),
+		RPDisplayName: passkeyDisplayName,
+		RPOrigins:     []string{origin.Scheme + "://" + origin.Host},
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyCeremonyExpiry},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: passkeyCeremonyExpiry},
+		},
+	})
+}
+
+// passkeyUser adapts a user and their stored credentials to webauthn.User. The
+// user handle is the raw user UUID.
+type passkeyUser struct {
+	id          uuid.UUID
+	email       string
+	name        string
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return u.id[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	if u.name != "" {
+		return u.name
+	}
+	return u.email
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// getPasskeys returns the WebAuthn relying party, or a 503 error when it could
+// not be configured at startup.
+func (am *AuthModule) getPasskeys() (*webauthn.WebAuthn, error) {
+	if am.passkeys == nil {
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, "Passkeys are not configured")
+	}
+	return am.passkeys, nil
+}
+
+// loadPasskeyUser loads a user together with their registered passkeys.
+func (am *AuthModule) loadPasskeyUser(ctx context.Context, userID uuid.UUID) (*passkeyUser, error) {
+	user, err := am.user.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := am.queries.ListUserWebauthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(rows))
+	for _, row := range rows {
+		credentials = append(credentials, toWebauthnCredential(row))
+	}
+
+	return &passkeyUser{
+		id:          user.ID,
+		email:       user.Email,
+		name:        user.Name,
+		credentials: credentials,
+	}, nil
+}
+
+func toWebauthnCredential(row authServices.WebauthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	for _, transport := range strings.Split(row.Transports, ",") {
+		if transport != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              row.CredentialID,
+		PublicKey:       row.PublicKey,
+		AttestationType: row.AttestationType,
+		Transport:       transports,
+		Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(row.Flags)),
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    row.Aaguid,
+			SignCount: uint32(row.SignCount),
+		},
+	}
+}
+
+func joinTransports(transports []protocol.AuthenticatorTransport) string {
+	values := make([]string, len(transports))
+	for i, transport := range transports {
+		values[i] = string(transport)
+	}
+	return strings.Join(values, ",")
+}
+
+// ceremonyToken stores the WebAuthn session data of a ceremony until it is
+// finished and returns a signed, short-lived token naming it, which the
+// client hands back on finish.
+func (am *AuthModule) ceremonyToken(ctx context.Context, typ string, session *webauthn.SessionData) (string, error) {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := am.queries.CreateWebauthnChallenge(ctx, authServices.CreateWebauthnChallengeParams{
+		Type:      typ,
+		Session:   raw,
+		ExpiresAt: time.Now().Add(passkeyCeremonyExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return am.jwt.GenerateFlowToken(typ, map[string]any{
+		"cid": id.String(),
+	}, passkeyCeremonyExpiry)
+}
+
+// ceremonySession validates a ceremony token and takes its session data out
+// of storage, so that a captured response to the challenge cannot be replayed.
+func (am *AuthModule) ceremonySession(ctx context.Context, tokenStr, typ string) (webauthn.SessionData, error) {
+	var session webauthn.SessionData
+	expired := fiber.NewError(fiber.StatusUnauthorized, "Passkey ceremony expired. Try again.")
+
+	claims, err := am.jwt.FlowTokenValidate(tokenStr, typ)
+	if err != nil {
+		return session, expired
+	}
+	cid, _ := claims["cid"].(string)
+	id, err := uuid.Parse(cid)
+	if err != nil {
+		return session, expired
+	}
+
+	raw, err := am.queries.ConsumeWebauthnChallenge(ctx, authServices.ConsumeWebauthnChallengeParams{
+		ID:   id,
+		Type: typ,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, expired
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return session, expired
+	}
+
+	return session, nil
+}
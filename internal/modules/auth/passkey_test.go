@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"varaden/server/config"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/gofiber/fiber/v2"
+)
+
+// testAuthenticator is a software passkey: one discoverable ES256 credential
+// that attests with the "none" format and always verifies the user.
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testAuthenticator{key: key, credentialID: credentialID}
+}
+
+// testCeremony is the response of a begin endpoint.
+type testCeremony struct {
+	Data struct {
+		Options struct {
+			PublicKey struct {
+				Challenge string `json:"challenge"`
+				User      struct {
+					ID string `json:"id"`
+				} `json:"user"`
+			} `json:"publicKey"`
+		} `json:"options"`
+		CeremonyToken string `json:"ceremony_token"`
+	} `json:"data"`
+}
+
+// create answers navigator.credentials.create() for the ceremony.
+func (a *testAuthenticator) create(t *testing.T, ceremony testCeremony) json.RawMessage {
+	t.Helper()
+
+	userHandle, err := base64.RawURLEncoding.DecodeString(ceremony.Data.Options.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Attested credential data: AAGUID, credential ID length, ID and key
+	authData := a.authData(protocol.FlagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]any{
+		"clientDataJSON":    a.clientData(t, protocol.CreateCeremony, ceremony),
+		"attestationObject": encodeTestBase64(attestationObject),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers navigator.credentials.get() for the ceremony.
+func (a *testAuthenticator) get(t *testing.T, ceremony testCeremony) json.RawMessage {
+	t.Helper()
+
+	a.signCount++
+	authData := a.authData(0)
+	clientData := a.clientData(t, protocol.AssertCeremony, ceremony)
+
+	rawClientData, err := base64.RawURLEncoding.DecodeString(clientData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]any{
+		"clientDataJSON":    clientData,
+		"authenticatorData": encodeTestBase64(authData),
+		"signature":         encodeTestBase64(signature),
+		"userHandle":        encodeTestBase64(a.userHandle),
+	})
+}
+
+// authData starts authenticator data for the RP ID, with the user present and
+// verified.
+func (a *testAuthenticator) authData(flags protocol.AuthenticatorFlags) []byte {
+	rp, _ := url.Parse(testFrontEndURL)
+	rpIDHash := sha256.Sum256([]byte(rp.Hostname()))
+
+	flags |= protocol.FlagUserPresent | protocol.FlagUserVerified
+	authData := append(rpIDHash[:], byte(flags))
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *testAuthenticator) clientData(t *testing.T, typ protocol.CeremonyType, ceremony testCeremony) string {
+	t.Helper()
+
+	raw, err := json.Marshal(map[string]any{
+		"type":      typ,
+		"challenge": ceremony.Data.Options.PublicKey.Challenge,
+		"origin":    testFrontEndURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeTestBase64(raw)
+}
+
+func (a *testAuthenticator) credential(t *testing.T, response map[string]any) json.RawMessage {
+	t.Helper()
+
+	raw, err := json.Marshal(map[string]any{
+		"id":                      encodeTestBase64(a.credentialID),
+		"rawId":                   encodeTestBase64(a.credentialID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"clientExtensionResults":  map[string]any{},
+		"response":                response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func encodeTestBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// postJSON sends body to the app, signed in with accessToken when it is set.
+func postJSON(t *testing.T, app *fiber.App, path, accessToken string, body any) (*http.Response, string) {
+	t.Helper()
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if accessToken != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+accessToken)
+	}
+	return doRequest(t, app, req)
+}
+
+// beginCeremony calls a begin endpoint and decodes the options it returns.
+func beginCeremony(t *testing.T, app *fiber.App, path, accessToken string) testCeremony {
+	t.Helper()
+
+	resp, body := postJSON(t, app, path, accessToken, nil)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("POST %s = %d %s, want 200", path, resp.StatusCode, body)
+	}
+
+	var ceremony testCeremony
+	if err := json.Unmarshal([]byte(body), &ceremony); err != nil {
+		t.Fatal(err)
+	}
+	return ceremony
+}
+
+// registerPasskey registers the authenticator for the signed-in user.
+func registerPasskey(t *testing.T, app *fiber.App, accessToken string, authenticator *testAuthenticator) {
+	t.Helper()
+
+	ceremony := beginCeremony(t, app, "/api/v1/auth/passkeys/register/begin", accessToken)
+	resp, body := postJSON(t, app, "/api/v1/auth/passkeys/register/finish", accessToken, passkeyRegisterData{
+		CeremonyToken: ceremony.Data.CeremonyToken,
+		Name:          "Test passkey",
+		Credential:    authenticator.create(t, ceremony),
+	})
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("register finish = %d %s, want 200", resp.StatusCode, body)
+	}
+}
+
+func TestPasskeyRegisterAndLogin(t *testing.T) {
+	app, am := newTestApp(t, config.OAuthConfig{})
+	userID := createUser(t, am, "passkey@example.test", true)
+	authenticator := newTestAuthenticator(t)
+
+	registerPasskey(t, app, signIn(t, am, userID), authenticator)
+
+	ceremony := beginCeremony(t, app, "/api/v1/auth/passkeys/login/begin", "")
+	resp, body := postJSON(t, app, "/api/v1/auth/passkeys/login/finish", "", passkeyLoginData{
+		CeremonyToken: ceremony.Data.CeremonyToken,
+		Credential:    authenticator.get(t, ceremony),
+	})
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("login finish = %d %s, want 200", resp.StatusCode, body)
+	}
+
+	var login struct {
+		Data struct {
+			Email string `json:"email"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &login); err != nil {
+		t.Fatal(err)
+	}
+	if login.Data.Email != "passkey@example.test" {
+		t.Errorf("signed in as %q, want passkey@example.test", login.Data.Email)
+	}
+}
+
+func TestPasskeyLoginRejectsReplay(t *testing.T) {
+	app, am := newTestApp(t, config.OAuthConfig{})
+	userID := createUser(t, am, "replay@example.test", true)
+	authenticator := newTestAuthenticator(t)
+	registerPasskey(t, app, signIn(t, am, userID), authenticator)
+
+	ceremony := beginCeremony(t, app, "/api/v1/auth/passkeys/login/begin", "")
+	assertion := passkeyLoginData{
+		CeremonyToken: ceremony.Data.CeremonyToken,
+		Credential:    authenticator.get(t, ceremony),
+	}
+	resp, body := postJSON(t, app, "/api/v1/auth/passkeys/login/finish", "", assertion)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("login finish = %d %s, want 200", resp.StatusCode, body)
+	}
+
+	// The challenge was taken out of storage by the first login
+	resp, body = postJSON(t, app, "/api/v1/auth/passkeys/login/finish", "", assertion)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("replayed login finish = %d %s, want 401", resp.StatusCode, body)
+	}
+}
+
+func TestPasskeyCeremonyTokenType(t *testing.T) {
+	app, am := newTestApp(t, config.OAuthConfig{})
+	userID := createUser(t, am, "ceremony@example.test", true)
+	accessToken := signIn(t, am, userID)
+	authenticator := newTestAuthenticator(t)
+
+	register := beginCeremony(t, app, "/api/v1/auth/passkeys/register/begin", accessToken)
+	credential := authenticator.create(t, register)
+
+	resp, body := postJSON(t, app, "/api/v1/auth/passkeys/login/finish", "", passkeyLoginData{
+		CeremonyToken: register.Data.CeremonyToken,
+		Credential:    credential,
+	})
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("login finish with a registration token = %d %s, want 401", resp.StatusCode, body)
+	}
+
+	login := beginCeremony(t, app, "/api/v1/auth/passkeys/login/begin", "")
+	resp, body = postJSON(t, app, "/api/v1/auth/passkeys/register/finish", accessToken, passkeyRegisterData{
+		CeremonyToken: login.Data.CeremonyToken,
+		Credential:    credential,
+	})
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("register finish with a login token = %d %s, want 401", resp.StatusCode, body)
+	}
+
+	// A token of the wrong type is turned away before its challenge is used up
+	resp, body = postJSON(t, app, "/api/v1/auth/passkeys/register/finish", accessToken, passkeyRegisterData{
+		CeremonyToken: register.Data.CeremonyToken,
+		Credential:    credential,
+	})
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("register finish = %d %s, want 200", resp.StatusCode, body)
+	}
+}
+
+func TestDeleteLastPasskey(t *testing.T) {
+	app, am := newTestApp(t, config.OAuthConfig{})
+	// No password or linked identity, so the passkeys are the only way in
+	userID := createUser(t, am, "last@example.test", true)
+	accessToken := signIn(t, am, userID)
+	registerPasskey(t, app, accessToken, newTestAuthenticator(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/passkeys", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+accessToken)
+	resp, body := doRequest(t, app, req)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("list passkeys = %d %s, want 200", resp.StatusCode, body)
+	}
+	var passkeys struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &passkeys); err != nil {
+		t.Fatal(err)
+	}
+	if len(passkeys.Data) != 1 {
+		t.Fatalf("listed %d passkeys, want 1", len(passkeys.Data))
+	}
+
+	deletePasskey := func() (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/auth/passkeys/"+passkeys.Data[0].ID, nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+accessToken)
+		return doRequest(t, app, req)
+	}
+
+	resp, body = deletePasskey()
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("delete only passkey = %d %s, want 409", resp.StatusCode, body)
+	}
+
+	// With a second passkey the first one can go
+	registerPasskey(t, app, accessToken, newTestAuthenticator(t))
+	resp, body = deletePasskey()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("delete passkey = %d %s, want 200", resp.StatusCode, body)
+	}
+}
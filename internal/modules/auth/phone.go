@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	phoneOTPExpiry = 10 * time.Minute
+
+	// At most one code per minute and five per hour to the same number
+	phoneOTPInterval    = time.Minute
+	phoneOTPWindow      = time.Hour
+	phoneOTPWindowLimit = 5
+)
+
+// throttlePhoneOTP records a code request for the number, or returns a 429
+// error when too many codes were requested for it recently. Requests are
+// counted whether or not a code is actually sent, so the limit does not reveal
+// which numbers are registered.
+func (am *AuthModule) throttlePhoneOTP(ctx context.Context, c *fiber.Ctx, phone string) error {
+	now := time.Now()
+
+	// Serialize requests for the number, so parallel ones cannot all see a
+	// count of zero
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.queries.WithTx(tx)
+
+	if err := qtx.LockPhoneOTPSends(ctx, phone); err != nil {
+		return err
+	}
+
+	// Forget sends that no longer count towards any limit
+	if err := qtx.DeleteStalePhoneOTPSends(ctx, authServices.DeleteStalePhoneOTPSendsParams{
+		Phone:  phone,
+		SentAt: now.Add(-phoneOTPWindow),
+	}); err != nil {
+		return err
+	}
+
+	recent, err := qtx.CountPhoneOTPSends(ctx, authServices.CountPhoneOTPSendsParams{
+		Phone:  phone,
+		SentAt: now.Add(-phoneOTPInterval),
+	})
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(phoneOTPInterval.Seconds())))
+		return fiber.NewError(fiber.StatusTooManyRequests, "A code was just sent to this number. Try again in a minute.")
+	}
+
+	sent, err := qtx.CountPhoneOTPSends(ctx, authServices.CountPhoneOTPSendsParams{
+		Phone:  phone,
+		SentAt: now.Add(-phoneOTPWindow),
+	})
+	if err != nil {
+		return err
+	}
+	if sent >= phoneOTPWindowLimit {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(phoneOTPWindow.Seconds())))
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many codes requested for this number. Try again later.")
+	}
+
+	if err := qtx.RecordPhoneOTPSend(ctx, phone); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sendPhoneOTP replaces the user's phone_verify token with a new code bound to
+// the number and texts it there.
+func (am *AuthModule) sendPhoneOTP(ctx context.Context, userID uuid.UUID, phone string) error {
+	otp := utils.GenerateRandomNumber()
+	if err := am.queries.IssueToken(ctx, userID, authServices.TokenTypePhoneVerify, otp, time.Now().Add(phoneOTPExpiry), sql.NullString{String: phone, Valid: true}); err != nil {
+		return err
+	}
+
+	return am.sms.SendSMS(phone, fmt.Sprintf("Your Varaden code is %s. It expires in %d minutes. Do not share it with anyone.", otp, int(phoneOTPExpiry.Minutes())))
+}
+
+// redeemPhoneOTP consumes the user's phone_verify code and returns the number
+// it was sent to. ok is false when the code is wrong or expired.
+func (am *AuthModule) redeemPhoneOTP(ctx context.Context, userID uuid.UUID, otp string) (phone string, ok bool, err error) {
+	token, ok, err := am.checkOTP(ctx, userID, authServices.TokenTypePhoneVerify, otp)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if token.ExpiresAt.Before(time.Now()) || !token.Target.Valid {
+		return "", false, nil
+	}
+
+	if err := am.queries.DeleteToken(ctx, token.ID); err != nil {
+		return "", false, err
+	}
+
+	return token.Target.String, true, nil
+}
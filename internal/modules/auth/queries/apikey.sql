@@ -0,0 +1,65 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id,
+    user_id,
+    name,
+    prefix,
+    key_hash,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at;
+-- name: GetAPIKeyByPrefix :one
+SELECT id,
+    user_id,
+    name,
+    prefix,
+    key_hash,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+FROM api_keys
+WHERE prefix = $1
+LIMIT 1;
+-- name: ListUserAPIKeys :many
+SELECT id,
+    user_id,
+    name,
+    prefix,
+    key_hash,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+FROM api_keys
+WHERE user_id = $1
+    AND revoked_at IS NULL
+ORDER BY created_at DESC;
+-- name: CountActiveUserAPIKeys :one
+SELECT COUNT(*)
+FROM api_keys
+WHERE user_id = $1
+    AND revoked_at IS NULL
+    AND (
+        expires_at IS NULL
+        OR expires_at > CURRENT_TIMESTAMP
+    );
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = CURRENT_TIMESTAMP
+WHERE id = $1
+    AND (
+        last_used_at IS NULL
+        OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute'
+    );
+-- name: RevokeUserAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,61 @@
+-- name: CreateAuthEvent :exec
+INSERT INTO auth_events (
+        event,
+        outcome,
+        reason,
+        user_id,
+        actor_id,
+        ip_address,
+        user_agent
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+-- name: ListUserAuthEvents :many
+SELECT *
+FROM auth_events
+WHERE user_id = sqlc.arg(user_id)
+    AND (
+        sqlc.narg(before_id)::BIGINT IS NULL
+        OR id < sqlc.narg(before_id)
+    )
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: ListAuthEvents :many
+SELECT *
+FROM auth_events
+WHERE (
+        sqlc.narg(user_id)::UUID IS NULL
+        OR user_id = sqlc.narg(user_id)
+    )
+    AND (
+        sqlc.narg(actor_id)::UUID IS NULL
+        OR actor_id = sqlc.narg(actor_id)
+    )
+    AND (
+        sqlc.narg(event)::VARCHAR IS NULL
+        OR event = sqlc.narg(event)
+    )
+    AND (
+        sqlc.narg(outcome)::VARCHAR IS NULL
+        OR outcome = sqlc.narg(outcome)
+    )
+    AND (
+        sqlc.narg(ip_address)::VARCHAR IS NULL
+        OR ip_address = sqlc.narg(ip_address)
+    )
+    AND (
+        sqlc.narg(since)::TIMESTAMP IS NULL
+        OR created_at >= sqlc.narg(since)
+    )
+    AND (
+        sqlc.narg(until)::TIMESTAMP IS NULL
+        OR created_at < sqlc.narg(until)
+    )
+    AND (
+        sqlc.narg(before_id)::BIGINT IS NULL
+        OR id < sqlc.narg(before_id)
+    )
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: DeleteAuthEventsBefore :execrows
+DELETE FROM auth_events
+WHERE created_at < $1;
\ No newline at end of file
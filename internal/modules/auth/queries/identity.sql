@@ -0,0 +1,45 @@
+-- name: GetIdentity :one
+SELECT id,
+    user_id,
+    provider,
+    subject,
+    email,
+    linked_at
+FROM user_identities
+WHERE provider = $1
+    AND subject = $2
+LIMIT 1;
+-- name: ListUserIdentities :many
+SELECT id,
+    user_id,
+    provider,
+    subject,
+    email,
+    linked_at
+FROM user_identities
+WHERE user_id = $1
+ORDER BY linked_at;
+-- name: CountUserIdentities :one
+SELECT COUNT(*)
+FROM user_identities
+WHERE user_id = $1;
+-- name: HasUserIdentity :one
+SELECT EXISTS (
+        SELECT 1
+        FROM user_identities
+        WHERE user_id = $1
+            AND provider = $2
+    );
+-- name: CreateIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4)
+RETURNING id,
+    user_id,
+    provider,
+    subject,
+    email,
+    linked_at;
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities
+WHERE user_id = $1
+    AND provider = $2;
\ No newline at end of file
@@ -0,0 +1,78 @@
+-- name: CreateInvitation :one
+INSERT INTO invitations (code, inviter_id, email, max_uses, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+-- name: GetInvitationByCode :one
+SELECT *
+FROM invitations
+WHERE code = $1
+LIMIT 1;
+-- name: RedeemInvitation :execrows
+UPDATE invitations
+SET uses = uses + 1
+WHERE id = $1
+    AND uses < max_uses
+    AND (
+        expires_at IS NULL
+        OR expires_at > CURRENT_TIMESTAMP
+    );
+-- name: CreateInvitationRedemption :exec
+INSERT INTO invitation_redemptions (invitation_id, user_id)
+VALUES ($1, $2);
+-- name: CountOpenUserInvitations :one
+SELECT COUNT(*)
+FROM invitations
+WHERE inviter_id = $1
+    AND uses < max_uses
+    AND (
+        expires_at IS NULL
+        OR expires_at > CURRENT_TIMESTAMP
+    );
+-- name: ListInvitations :many
+SELECT i.id,
+    i.code,
+    i.inviter_id,
+    i.email,
+    i.max_uses,
+    i.uses,
+    i.expires_at,
+    i.created_at,
+    COALESCE(
+        (
+            SELECT json_agg(
+                    json_build_object(
+                        'user_id',
+                        r.user_id,
+                        'redeemed_at',
+                        r.redeemed_at
+                    )
+                    ORDER BY r.redeemed_at
+                )
+            FROM invitation_redemptions r
+            WHERE r.invitation_id = i.id
+        ),
+        '[]'
+    )::JSON AS invitees
+FROM invitations i
+WHERE (
+        sqlc.narg(inviter_id)::UUID IS NULL
+        OR i.inviter_id = sqlc.narg(inviter_id)
+    )
+    AND (
+        sqlc.narg(before)::TIMESTAMP IS NULL
+        OR i.created_at < sqlc.narg(before)
+        OR (
+            i.created_at = sqlc.narg(before)
+            AND i.id < sqlc.narg(before_id)::UUID
+        )
+    )
+ORDER BY i.created_at DESC,
+    i.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetUserInvitationRedemption :one
+SELECT r.invitation_id,
+    i.inviter_id,
+    r.redeemed_at
+FROM invitation_redemptions r
+    JOIN invitations i ON i.id = r.invitation_id
+WHERE r.user_id = $1;
\ No newline at end of file
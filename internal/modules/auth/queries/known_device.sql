@@ -0,0 +1,82 @@
+-- name: GetDeviceFamiliarity :one
+SELECT EXISTS (
+        SELECT 1
+        FROM known_devices
+        WHERE user_id = $1
+    ) AS has_devices,
+    EXISTS (
+        SELECT 1
+        FROM known_devices
+        WHERE user_id = $1
+            AND device_hash = $2
+    ) AS known_device,
+    EXISTS (
+        SELECT 1
+        FROM known_devices
+        WHERE user_id = $1
+            AND network = $3
+    ) AS known_network,
+    EXISTS (
+        SELECT 1
+        FROM known_devices
+        WHERE user_id = $1
+            AND country_code = $4
+    ) AS known_country;
+-- name: GetLastKnownDevice :one
+SELECT id,
+    user_id,
+    device_hash,
+    network,
+    user_agent,
+    ip_address,
+    country_code,
+    location,
+    latitude,
+    longitude,
+    accuracy_km,
+    first_seen_at,
+    last_seen_at
+FROM known_devices
+WHERE user_id = $1
+ORDER BY last_seen_at DESC
+LIMIT 1;
+-- name: UpsertKnownDevice :one
+INSERT INTO known_devices (
+        user_id,
+        device_hash,
+        network,
+        user_agent,
+        ip_address,
+        country_code,
+        location,
+        latitude,
+        longitude,
+        accuracy_km
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (user_id, device_hash, network) DO
+UPDATE
+SET user_agent = EXCLUDED.user_agent,
+    ip_address = EXCLUDED.ip_address,
+    country_code = EXCLUDED.country_code,
+    location = EXCLUDED.location,
+    latitude = EXCLUDED.latitude,
+    longitude = EXCLUDED.longitude,
+    accuracy_km = EXCLUDED.accuracy_km,
+    last_seen_at = CURRENT_TIMESTAMP
+RETURNING id,
+    user_id,
+    device_hash,
+    network,
+    user_agent,
+    ip_address,
+    country_code,
+    location,
+    latitude,
+    longitude,
+    accuracy_km,
+    first_seen_at,
+    last_seen_at;
+-- name: DeleteKnownDevice :exec
+DELETE FROM known_devices
+WHERE id = $1
+    AND user_id = $2;
\ No newline at end of file
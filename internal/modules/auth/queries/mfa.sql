@@ -0,0 +1,46 @@
+-- name: GetUserMFA :one
+SELECT user_id,
+    totp_secret,
+    enabled_at,
+    last_used_step,
+    created_at
+FROM user_mfa
+WHERE user_id = $1
+LIMIT 1;
+-- name: UpsertPendingMFA :execrows
+INSERT INTO user_mfa (user_id, totp_secret)
+VALUES ($1, $2) ON CONFLICT (user_id) DO
+UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+    last_used_step = 0,
+    created_at = CURRENT_TIMESTAMP
+WHERE user_mfa.enabled_at IS NULL;
+-- name: EnableMFA :exec
+UPDATE user_mfa
+SET enabled_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE user_id = $1
+    AND last_used_step < $2;
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE user_id = $1;
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+    AND code_hash = $2
+    AND used_at IS NULL;
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM mfa_recovery_codes
+WHERE user_id = $1
+    AND used_at IS NULL;
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
\ No newline at end of file
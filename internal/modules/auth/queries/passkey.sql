@@ -0,0 +1,78 @@
+-- name: CreateWebauthnCredential :one
+INSERT INTO webauthn_credentials (
+        user_id,
+        credential_id,
+        public_key,
+        attestation_type,
+        transports,
+        aaguid,
+        sign_count,
+        flags,
+        name
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id,
+    user_id,
+    credential_id,
+    public_key,
+    attestation_type,
+    transports,
+    aaguid,
+    sign_count,
+    flags,
+    name,
+    last_used_at,
+    created_at;
+-- name: GetWebauthnCredential :one
+SELECT id,
+    user_id,
+    credential_id,
+    public_key,
+    attestation_type,
+    transports,
+    aaguid,
+    sign_count,
+    flags,
+    name,
+    last_used_at,
+    created_at
+FROM webauthn_credentials
+WHERE credential_id = $1
+LIMIT 1;
+-- name: ListUserWebauthnCredentials :many
+SELECT id,
+    user_id,
+    credential_id,
+    public_key,
+    attestation_type,
+    transports,
+    aaguid,
+    sign_count,
+    flags,
+    name,
+    last_used_at,
+    created_at
+FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at;
+-- name: CountUserWebauthnCredentials :one
+SELECT COUNT(*)
+FROM webauthn_credentials
+WHERE user_id = $1;
+-- name: HasUserWebauthnCredential :one
+SELECT EXISTS (
+        SELECT 1
+        FROM webauthn_credentials
+        WHERE id = $1
+            AND user_id = $2
+    );
+-- name: UpdateWebauthnCredentialUsage :exec
+UPDATE webauthn_credentials
+SET sign_count = $2,
+    flags = $3,
+    last_used_at = CURRENT_TIMESTAMP
+WHERE credential_id = $1;
+-- name: DeleteUserWebauthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1
+    AND user_id = $2;
\ No newline at end of file
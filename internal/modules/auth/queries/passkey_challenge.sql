@@ -0,0 +1,14 @@
+-- name: CreateWebauthnChallenge :one
+WITH expired AS (
+    DELETE FROM webauthn_challenges
+    WHERE expires_at <= CURRENT_TIMESTAMP
+)
+INSERT INTO webauthn_challenges (type, session, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id;
+-- name: ConsumeWebauthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE id = $1
+    AND type = $2
+    AND expires_at > CURRENT_TIMESTAMP
+RETURNING session;
\ No newline at end of file
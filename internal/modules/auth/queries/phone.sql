@@ -0,0 +1,14 @@
+-- name: CountPhoneOTPSends :one
+SELECT COUNT(*)
+FROM phone_otp_sends
+WHERE phone = $1
+    AND sent_at > $2;
+-- name: LockPhoneOTPSends :exec
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(phone)::TEXT));
+-- name: RecordPhoneOTPSend :exec
+INSERT INTO phone_otp_sends (phone)
+VALUES ($1);
+-- name: DeleteStalePhoneOTPSends :exec
+DELETE FROM phone_otp_sends
+WHERE phone = $1
+    AND sent_at < $2;
\ No newline at end of file
@@ -1,42 +1,74 @@
--- name: GetToken :one
+-- name: GetUserToken :one
 SELECT id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
+    created_at,
+    target,
+    token_hash,
+    attempts
 FROM tokens
-WHERE token = $1
+WHERE user_id = $1
     AND type = $2
-    AND user_id = $3
 LIMIT 1;
--- name: GetTokenByUserId :one
+-- name: GetTokenByHash :one
 SELECT id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
+    created_at,
+    target,
+    token_hash,
+    attempts
 FROM tokens
-WHERE user_id = $1;
--- name: GetTokenByCode :one
-SELECT id,
+WHERE token_hash = $1
+LIMIT 1;
+-- name: CreateToken :one
+INSERT INTO tokens (user_id, token_hash, type, expires_at, target)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
-FROM tokens
-WHERE token = $1;
--- name: CreateToken :one
-INSERT INTO tokens (user_id, token, type, expires_at)
-VALUES ($1, $2, $3, $4)
+    created_at,
+    target,
+    token_hash,
+    attempts;
+-- name: IncrementTokenAttempts :one
+UPDATE tokens
+SET attempts = attempts + 1
+WHERE user_id = sqlc.arg(user_id)
+    AND type = sqlc.arg(type)
+    AND attempts < sqlc.arg(max_attempts)
 RETURNING id,
     user_id,
     token,
     type,
     expires_at,
-    created_at;
+    created_at,
+    target,
+    token_hash,
+    attempts;
 -- name: DeleteToken :exec
 DELETE FROM tokens
+WHERE id = $1;
+-- name: DeleteUserTokens :exec
+DELETE FROM tokens
+WHERE user_id = $1
+    AND type = $2;
+-- name: ListUnhashedTokens :many
+SELECT id,
+    user_id,
+    type,
+    token
+FROM tokens
+WHERE token_hash IS NULL
+    AND token IS NOT NULL;
+-- name: SetTokenHash :exec
+UPDATE tokens
+SET token_hash = $2,
+    token = NULL
 WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- name: HitRateLimit :one
+INSERT INTO rate_limit_hits (key, window_start, expires_at)
+VALUES ($1, $2, $3) ON CONFLICT (key, window_start) DO
+UPDATE
+SET hits = rate_limit_hits.hits + 1
+RETURNING hits;
+-- name: GetRateLimitHits :one
+SELECT hits
+FROM rate_limit_hits
+WHERE key = $1
+    AND window_start = $2
+LIMIT 1;
+-- name: DeleteExpiredRateLimitHits :exec
+DELETE FROM rate_limit_hits
+WHERE expires_at < CURRENT_TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,96 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+        family_id,
+        user_id,
+        user_agent,
+        ip_address,
+        authenticated_at,
+        expires_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id,
+    family_id,
+    user_id,
+    user_agent,
+    ip_address,
+    authenticated_at,
+    rotated_at,
+    revoked_at,
+    expires_at,
+    created_at;
+-- name: GetSessionForUpdate :one
+SELECT id,
+    family_id,
+    user_id,
+    user_agent,
+    ip_address,
+    authenticated_at,
+    rotated_at,
+    revoked_at,
+    expires_at,
+    created_at
+FROM sessions
+WHERE id = $1
+LIMIT 1 FOR
+UPDATE;
+-- name: RotateSession :exec
+UPDATE sessions
+SET rotated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+-- name: RotateSessionFamily :exec
+UPDATE sessions
+SET rotated_at = CURRENT_TIMESTAMP
+WHERE family_id = $1
+    AND rotated_at IS NULL
+    AND revoked_at IS NULL;
+-- name: RevokeSessionFamily :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE family_id = $1
+    AND revoked_at IS NULL;
+-- name: GetSessionFamilyAuthenticatedAt :one
+SELECT authenticated_at
+FROM sessions
+WHERE family_id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1;
+-- name: IsSessionFamilyActive :one
+SELECT EXISTS (
+        SELECT 1
+        FROM sessions
+        WHERE family_id = $1
+            AND revoked_at IS NULL
+            AND expires_at > CURRENT_TIMESTAMP
+    );
+-- name: ListActiveSessions :many
+SELECT family_id AS id,
+    user_agent,
+    ip_address,
+    authenticated_at AS created_at,
+    created_at AS last_used_at,
+    expires_at
+FROM sessions
+WHERE user_id = $1
+    AND rotated_at IS NULL
+    AND revoked_at IS NULL
+    AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC;
+-- name: RevokeUserSessionFamily :execrows
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE family_id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL;
+-- name: RevokeOtherUserSessions :execrows
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+    AND family_id <> $2
+    AND revoked_at IS NULL;
+-- name: RevokeAllUserSessions :execrows
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+    AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,85 @@
+-- name: ListUserSessions :many
+SELECT id,
+    family_id,
+    user_id,
+    user_agent,
+    ip_address,
+    authenticated_at,
+    rotated_at,
+    revoked_at,
+    expires_at,
+    created_at
+FROM sessions
+WHERE user_id = $1
+ORDER BY created_at;
+-- name: ListKnownDevices :many
+SELECT id,
+    user_id,
+    device_hash,
+    network,
+    user_agent,
+    ip_address,
+    country_code,
+    location,
+    latitude,
+    longitude,
+    accuracy_km,
+    first_seen_at,
+    last_seen_at
+FROM known_devices
+WHERE user_id = $1
+ORDER BY first_seen_at;
+-- name: DeleteUserAuthData :exec
+WITH deleted_sessions AS (
+    DELETE FROM sessions
+    WHERE user_id = $1
+),
+deleted_tokens AS (
+    DELETE FROM tokens
+    WHERE user_id = $1
+),
+deleted_identities AS (
+    DELETE FROM user_identities
+    WHERE user_id = $1
+),
+deleted_mfa AS (
+    DELETE FROM user_mfa
+    WHERE user_id = $1
+),
+deleted_recovery_codes AS (
+    DELETE FROM mfa_recovery_codes
+    WHERE user_id = $1
+),
+deleted_passkeys AS (
+    DELETE FROM webauthn_credentials
+    WHERE user_id = $1
+),
+deleted_api_keys AS (
+    DELETE FROM api_keys
+    WHERE user_id = $1
+),
+deleted_invitations AS (
+    DELETE FROM invitations
+    WHERE inviter_id = $1
+        AND uses = 0
+),
+deleted_phone_otp_sends AS (
+    DELETE FROM phone_otp_sends
+    WHERE phone = (
+            SELECT phone
+            FROM users
+            WHERE id = $1
+        )
+),
+scrubbed_auth_events AS (
+    UPDATE auth_events
+    SET ip_address = '',
+        user_agent = ''
+    WHERE actor_id = $1
+        OR (
+            user_id = $1
+            AND actor_id IS NULL
+        )
+)
+DELETE FROM known_devices
+WHERE user_id = $1;
\ No newline at end of file
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/services"
+)
+
+// rateLimitCounters keeps the counters of the postgres rate limit store in the
+// rate_limit_hits table.
+type rateLimitCounters struct {
+	queries *authServices.Queries
+}
+
+func NewRateLimitCounters(db *sql.DB) services.RateLimitCounters {
+	return &rateLimitCounters{queries: authServices.New(db)}
+}
+
+func (rc *rateLimitCounters) Hit(ctx context.Context, key string, windowStart int64, expiresAt time.Time) (int64, error) {
+	return rc.queries.HitRateLimit(ctx, authServices.HitRateLimitParams{
+		Key:         key,
+		WindowStart: windowStart,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+func (rc *rateLimitCounters) Hits(ctx context.Context, key string, windowStart int64) (int64, error) {
+	hits, err := rc.queries.GetRateLimitHits(ctx, authServices.GetRateLimitHitsParams{
+		Key:         key,
+		WindowStart: windowStart,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return hits, err
+}
+
+func (rc *rateLimitCounters) DeleteExpired(ctx context.Context) error {
+	return rc.queries.DeleteExpiredRateLimitHits(ctx)
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+const (
+	stepUpTokenType = "login_step_up"
+	stepUpExpiry    = 10 * time.Minute
+
+	loginAlertExpiry = 7 * 24 * time.Hour
+
+	// Risk signals of a sign-in
+	signalNewDevice        = "new_device"
+	signalNewNetwork       = "new_network"
+	signalNewCountry       = "new_country"
+	signalImpossibleTravel = "impossible_travel"
+
+	earthRadiusKM = 6371.0
+)
+
+// riskWeights is what each signal adds to a sign-in's risk score, which is
+// capped at 100.
+var riskWeights = map[string]int{
+	signalNewDevice:        20,
+	signalNewNetwork:       20,
+	signalNewCountry:       30,
+	signalImpossibleTravel: 60,
+}
+
+// versionPattern matches version numbers in user agents.
+var versionPattern = regexp.MustCompile(`[0-9][0-9._]*`)
+
+// loginRisk describes how a sign-in compares to the user's earlier ones.
+type loginRisk struct {
+	Score   int
+	Signals []string
+
+	device   string
+	network  string
+	location services.GeoLocation
+	located  bool
+}
+
+// assessLogin scores the request's sign-in against the devices, networks and
+// locations the user signed in from before. A user's first sign-in has
+// nothing to compare with and scores 0.
+func (am *AuthModule) assessLogin(ctx context.Context, c *fiber.Ctx, userID uuid.UUID) (loginRisk, error) {
+	risk := loginRisk{
+		device:  deviceHash(c.Get(fiber.HeaderUserAgent)),
+		network: ipNetwork(c.IP()),
+	}
+	risk.location, risk.located = am.geoip.Lookup(c.IP())
+
+	familiarity, err := am.queries.GetDeviceFamiliarity(ctx, authServices.GetDeviceFamiliarityParams{
+		UserID:      userID,
+		DeviceHash:  risk.device,
+		Network:     risk.network,
+		CountryCode: risk.location.CountryCode,
+	})
+	if err != nil {
+		return loginRisk{}, err
+	}
+	if !familiarity.HasDevices {
+		return risk, nil
+	}
+
+	if !familiarity.KnownDevice {
+		risk.add(signalNewDevice)
+	}
+	if !familiarity.KnownNetwork {
+		risk.add(signalNewNetwork)
+	}
+	if risk.located && !familiarity.KnownCountry {
+		risk.add(signalNewCountry)
+	}
+
+	if risk.located {
+		last, err := am.queries.GetLastKnownDevice(ctx, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return loginRisk{}, err
+		}
+		if err == nil && am.impossibleTravel(last, risk.location) {
+			risk.add(signalImpossibleTravel)
+		}
+	}
+
+	return risk, nil
+}
+
+func (r *loginRisk) add(signal string) {
+	r.Signals = append(r.Signals, signal)
+	r.Score = min(r.Score+riskWeights[signal], 100)
+}
+
+// impossibleTravel reports whether getting from the previous sign-in's
+// location to loc since then would take more than the maximum travel speed.
+// Both locations are given the benefit of their accuracy radius.
+func (am *AuthModule) impossibleTravel(last authServices.KnownDevice, loc services.GeoLocation) bool {
+	if !last.Latitude.Valid || !last.Longitude.Valid {
+		return false
+	}
+
+	distance := haversineKM(last.Latitude.Float64, last.Longitude.Float64, loc.Latitude, loc.Longitude)
+	distance -= float64(last.AccuracyKm) + float64(loc.AccuracyKM)
+	if distance <= 0 {
+		return false
+	}
+
+	hours := math.Max(time.Since(last.LastSeenAt).Hours(), 1.0/60)
+	return distance/hours > am.risk.MaxTravelSpeed
+}
+
+// rememberDevice records the request's device and network as known to the
+// user, along with where the sign-in came from.
+func (am *AuthModule) rememberDevice(ctx context.Context, c *fiber.Ctx, userID uuid.UUID, risk loginRisk) (authServices.KnownDevice, error) {
+	params := authServices.UpsertKnownDeviceParams{
+		UserID:     userID,
+		DeviceHash: risk.device,
+		Network:    risk.network,
+		UserAgent:  utils.Truncate(c.Get(fiber.HeaderUserAgent), 512),
+		IpAddress:  c.IP(),
+	}
+	if risk.located {
+		params.CountryCode = risk.location.CountryCode
+		params.Location = utils.Truncate(formatLocation(risk.location), 255)
+		params.Latitude = sql.NullFloat64{Float64: risk.location.Latitude, Valid: true}
+		params.Longitude = sql.NullFloat64{Float64: risk.location.Longitude, Valid: true}
+		params.AccuracyKm = int32(risk.location.AccuracyKM)
+	}
+
+	return am.queries.UpsertKnownDevice(ctx, params)
+}
+
+// requiresStepUp reports whether a sign-in must be confirmed with an emailed
+// code. Sign-ins through an emailed link already prove access to the inbox.
+func (am *AuthModule) requiresStepUp(risk loginRisk, method string) bool {
+	if !am.risk.StepUp || risk.Score < am.risk.StepUpScore {
+		return false
+	}
+	return method != "magic_link" && method != "email_verify"
+}
+
+// startStepUp emails the user a one-time code and responds with a
+// login_step_up token to redeem it with at /auth/login/verify.
+func (am *AuthModule) startStepUp(ctx context.Context, c *fiber.Ctx, userID uuid.UUID, email, method string, risk loginRisk) error {
+	code := utils.GenerateRandomNumber()
+	if err := am.queries.IssueToken(ctx, userID, authServices.TokenTypeLoginStepUp, code, time.Now().Add(stepUpExpiry), sql.NullString{}); err != nil {
+		return err
+	}
+	if err := am.SendLoginCodeEmail(email, code); err != nil {
+		return err
+	}
+
+	stepUpToken, err := am.jwt.GenerateFlowToken(stepUpTokenType, map[string]any{
+		"sub":    userID.String(),
+		"method": method,
+	}, stepUpExpiry)
+	if err != nil {
+		return err
+	}
+	am.logEvent(ctx, c, eventLoginChallenge, userID, strings.Join(risk.Signals, ","))
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"id":               userID,
+			"step_up_required": true,
+			"step_up_token":    stepUpToken,
+		},
+	})
+}
+
+// trackDevice remembers the sign-in's device and, when the sign-in differs
+// from the user's earlier ones, emails them a link to report it that signs
+// out every device. Failures are logged rather than failing the sign-in.
+func (am *AuthModule) trackDevice(ctx context.Context, c *fiber.Ctx, userID uuid.UUID, email string, risk loginRisk) {
+	device, err := am.rememberDevice(ctx, c, userID, risk)
+	if err != nil {
+		log.Errorf("failed to remember device for user %s: %v", userID, err)
+		return
+	}
+	if len(risk.Signals) == 0 {
+		return
+	}
+	am.logEvent(ctx, c, eventLoginAlert, userID, strings.Join(risk.Signals, ","))
+
+	// Every alert gets its own link, so earlier ones keep working
+	reportToken := utils.GenerateRandomString(32)
+	if _, err := am.queries.CreateToken(ctx, authServices.CreateTokenParams{
+		UserID:    userID,
+		TokenHash: authServices.HashToken(userID, authServices.TokenTypeLoginAlert, reportToken),
+		Type:      authServices.TokenTypeLoginAlert,
+		ExpiresAt: time.Now().Add(loginAlertExpiry),
+		Target:    sql.NullString{String: device.ID.String(), Valid: true},
+	}); err != nil {
+		log.Errorf("failed to create login alert token for user %s: %v", userID, err)
+		return
+	}
+	if err := am.SendNewDeviceEmail(email, device, risk.Signals, reportToken); err != nil {
+		log.Errorf("failed to send new device email to user %s: %v", userID, err)
+	}
+}
+
+// deviceHash identifies a device by its user agent without version numbers,
+// so that browser and OS updates do not make it look new.
+func deviceHash(userAgent string) string {
+	normalized := versionPattern.ReplaceAllString(strings.ToLower(userAgent), "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// ipNetwork returns the /24 (IPv4) or /48 (IPv6) network of ip, which stays
+// the same while an ISP hands out nearby addresses.
+func ipNetwork(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ip
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: addr.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// haversineKM is the great-circle distance between two coordinates.
+func haversineKM(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// formatLocation describes a location as "City, Country".
+func formatLocation(loc services.GeoLocation) string {
+	var parts []string
+	for _, part := range []string{loc.City, loc.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeDevice names the browser and operating system of a user agent for
+// emails, such as "Firefox on Windows".
+func describeDevice(userAgent string) string {
+	browser := firstMatch(userAgent, [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	})
+	os := firstMatch(userAgent, [][2]string{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	})
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	case userAgent != "":
+		return utils.Truncate(userAgent, 100)
+	default:
+		return "Unknown device"
+	}
+}
+
+// firstMatch returns the name paired with the first marker found in s.
+func firstMatch(s string, markers [][2]string) string {
+	for _, marker := range markers {
+		if strings.Contains(s, marker[0]) {
+			return marker[1]
+		}
+	}
+	return ""
+}
@@ -1,15 +1,93 @@
 package auth
 
+import (
+	"varaden/server/internal/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
+
 func (am *AuthModule) SetupRoutes() {
 	auth := am.route.Group("/auth")
+	protected := middlewares.Protected(am.db)
+	// Sensitive account changes are left to the user, not staff acting as them
+	sensitive := middlewares.DenyImpersonation()
+
+	// Brute-force protection on top of the per-account lock
+	byIP := middlewares.RateLimitByIP(am.limits.IPLimit, am.limits.IPWindow)
+	byEmail := middlewares.RateLimitByBodyField("email", am.limits.AccountLimit, am.limits.AccountWindow)
+	byUserID := middlewares.RateLimitByBodyField("user_id", am.limits.AccountLimit, am.limits.AccountWindow)
+	byPhone := middlewares.RateLimitByBodyField("phone", am.limits.AccountLimit, am.limits.AccountWindow)
 
 	auth.Post("/register", am.register)
-	auth.Post("/login", am.login)
-	auth.Post("/refresh", am.refreshTokens)
-	auth.Post("/forgot-password", am.forgotPassword)
-	auth.Post("/reset-password", am.resetPassword)
-	auth.Post("/send-verification-email", am.sendVerificationEmail)
-	auth.Post("/verify-email", am.verifyEmail)
+	auth.Post("/login", middlewares.RateLimit(am.limiter, "login", byIP, byEmail), am.login)
+	auth.Post("/login/verify", middlewares.RateLimit(am.limiter, "login-verify", byIP), am.verifyStepUp)
+	auth.Post("/report-login", middlewares.RateLimit(am.limiter, "report-login", byIP), am.reportLogin)
+	// The refresh token cookie is sent automatically, so guard against
+	// requests forged by other sites
+	auth.Post("/refresh", middlewares.RequireTrustedOrigin(am.cors.AllowOrigins), middlewares.RequireCSRFToken(am.jwt), am.refreshTokens)
+	auth.Post("/forgot-password", middlewares.RateLimit(am.limiter, "forgot-password", byIP, byEmail), am.forgotPassword)
+	auth.Post("/reset-password", middlewares.RateLimit(am.limiter, "reset-password", byIP), am.resetPassword)
+	auth.Post("/unlock-account", middlewares.RateLimit(am.limiter, "unlock-account", byIP), am.unlockAccount)
+	auth.Post("/restore-account", middlewares.RateLimit(am.limiter, "restore-account", byIP), am.restoreAccount)
+	auth.Post("/send-verification-email", middlewares.RateLimit(am.limiter, "send-verification-email", byIP, byUserID), am.sendVerificationEmail)
+	auth.Post("/verify-email", middlewares.RateLimit(am.limiter, "verify-email", byIP, byUserID), am.verifyEmail)
+	auth.Post("/magic-link", middlewares.RateLimit(am.limiter, "magic-link", byIP, byEmail), am.requestMagicLink)
+	auth.Post("/magic-link/login", am.magicLinkLogin)
+	auth.Post("/change-email/confirm", am.confirmEmailChange)
+	auth.Post("/change-email/undo", am.undoEmailChange)
 	auth.Get("/google", am.googleLogin)
 	auth.Get("/google-callback", am.googleCallback)
+	auth.Post("/mfa/verify", middlewares.RateLimit(am.limiter, "mfa-verify", byIP), am.verifyMFA)
+	auth.Post("/passkeys/login/begin", middlewares.RateLimit(am.limiter, "passkey-login-begin", byIP), am.beginPasskeyLogin)
+	auth.Post("/passkeys/login/finish", am.finishPasskeyLogin)
+	auth.Post("/phone/login/send", middlewares.RateLimit(am.limiter, "phone-login-send", byIP), am.sendPhoneLoginCode)
+	auth.Post("/phone/login", middlewares.RateLimit(am.limiter, "phone-login", byIP, byPhone), am.phoneLogin)
+
+	auth.Post("/change-password", protected, sensitive, am.changePassword)
+	auth.Post("/change-email", protected, sensitive, am.requestEmailChange)
+
+	auth.Get("/activity", protected, am.listActivity)
+
+	auth.Get("/sessions", protected, am.listSessions)
+	auth.Delete("/sessions/:id", protected, sensitive, am.revokeSession)
+	auth.Post("/logout-all", protected, sensitive, am.logoutAll)
+
+	auth.Get("/identities", protected, am.listIdentities)
+	auth.Post("/identities/google", protected, sensitive, am.linkGoogle)
+	auth.Delete("/identities/:provider", protected, sensitive, am.unlinkIdentity)
+
+	auth.Get("/mfa", protected, am.mfaStatus)
+	auth.Post("/mfa/totp/setup", protected, sensitive, am.setupTOTP)
+	auth.Post("/mfa/totp/confirm", protected, sensitive, am.confirmTOTP)
+	auth.Post("/mfa/recovery-codes", protected, sensitive, am.regenerateRecoveryCodes)
+	auth.Post("/mfa/disable", protected, sensitive, am.disableMFA)
+
+	auth.Get("/passkeys", protected, am.listPasskeys)
+	auth.Post("/passkeys/register/begin", protected, sensitive, am.beginPasskeyRegistration)
+	auth.Post("/passkeys/register/finish", protected, sensitive, am.finishPasskeyRegistration)
+	auth.Delete("/passkeys/:id", protected, sensitive, am.deletePasskey)
+
+	auth.Post("/phone", protected, sensitive, am.addPhone)
+	auth.Post("/phone/verify", protected, sensitive, am.verifyPhone)
+
+	auth.Get("/api-keys", protected, am.listAPIKeys)
+	auth.Post("/api-keys", protected, sensitive, am.createAPIKey)
+	auth.Delete("/api-keys/:id", protected, sensitive, am.revokeAPIKey)
+
+	auth.Get("/invitations", protected, am.listInvitations)
+	auth.Post("/invitations", protected, sensitive, am.createInvitation)
+
+	auth.Post("/impersonation/stop", protected, am.stopImpersonation)
+
+	am.admin.Delete("/users/:id/sessions", am.adminOnly, middlewares.RequirePermission("sessions:revoke"), am.forceLogout)
+	am.admin.Get("/auth-events", am.adminOnly, middlewares.RequirePermission("audit:read"), am.listAuthEvents)
+	am.admin.Get("/invitations", am.adminOnly, middlewares.RequirePermission(invitationsPermission), am.listAllInvitations)
+	am.admin.Post("/invitations", am.adminOnly, middlewares.RequirePermission(invitationsPermission), am.createAdminInvitation)
+	am.admin.Post("/users/:id/impersonate", am.adminOnly, middlewares.RequirePermission(middlewares.ImpersonatePermission), am.startImpersonation)
+}
+
+// SetupWellKnownRoutes registers the routes that live at the root of the app
+// rather than under the API version prefix.
+func (am *AuthModule) SetupWellKnownRoutes(app fiber.Router) {
+	app.Get("/.well-known/jwks.json", am.jwks)
 }
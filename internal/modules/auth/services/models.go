@@ -5,7 +5,9 @@
 package authServices
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -15,9 +17,16 @@ import (
 type TokenType string
 
 const (
-	TokenTypeEmailVerify   TokenType = "email_verify"
-	TokenTypePhoneVerify   TokenType = "phone_verify"
-	TokenTypePasswordReset TokenType = "password_reset"
+	TokenTypeEmailVerify     TokenType = "email_verify"
+	TokenTypePhoneVerify     TokenType = "phone_verify"
+	TokenTypePasswordReset   TokenType = "password_reset"
+	TokenTypeMagicLink       TokenType = "magic_link"
+	TokenTypeEmailChange     TokenType = "email_change"
+	TokenTypeEmailChangeUndo TokenType = "email_change_undo"
+	TokenTypeAccountUnlock   TokenType = "account_unlock"
+	TokenTypeLoginStepUp     TokenType = "login_step_up"
+	TokenTypeLoginAlert      TokenType = "login_alert"
+	TokenTypeAccountRestore  TokenType = "account_restore"
 )
 
 func (e *TokenType) Scan(src interface{}) error {
@@ -55,11 +64,146 @@ func (ns NullTokenType) Value() (driver.Value, error) {
 	return string(ns.TokenType), nil
 }
 
+type ApiKey struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	KeyHash    string       `json:"key_hash"`
+	Scopes     string       `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type AuthEvent struct {
+	ID        int64         `json:"id"`
+	Event     string        `json:"event"`
+	Outcome   string        `json:"outcome"`
+	Reason    string        `json:"reason"`
+	UserID    uuid.NullUUID `json:"user_id"`
+	ActorID   uuid.NullUUID `json:"actor_id"`
+	IpAddress string        `json:"ip_address"`
+	UserAgent string        `json:"user_agent"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type Invitation struct {
+	ID        uuid.UUID      `json:"id"`
+	Code      string         `json:"code"`
+	InviterID uuid.UUID      `json:"inviter_id"`
+	Email     sql.NullString `json:"email"`
+	MaxUses   int32          `json:"max_uses"`
+	Uses      int32          `json:"uses"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type InvitationRedemption struct {
+	InvitationID uuid.UUID `json:"invitation_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	RedeemedAt   time.Time `json:"redeemed_at"`
+}
+
+type KnownDevice struct {
+	ID          uuid.UUID       `json:"id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	DeviceHash  string          `json:"device_hash"`
+	Network     string          `json:"network"`
+	UserAgent   string          `json:"user_agent"`
+	IpAddress   string          `json:"ip_address"`
+	CountryCode string          `json:"country_code"`
+	Location    string          `json:"location"`
+	Latitude    sql.NullFloat64 `json:"latitude"`
+	Longitude   sql.NullFloat64 `json:"longitude"`
+	AccuracyKm  int32           `json:"accuracy_km"`
+	FirstSeenAt time.Time       `json:"first_seen_at"`
+	LastSeenAt  time.Time       `json:"last_seen_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PhoneOtpSend struct {
+	ID     uuid.UUID `json:"id"`
+	Phone  string    `json:"phone"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+type RateLimitHit struct {
+	Key         string    `json:"key"`
+	WindowStart int64     `json:"window_start"`
+	Hits        int64     `json:"hits"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type Session struct {
+	ID              uuid.UUID    `json:"id"`
+	FamilyID        uuid.UUID    `json:"family_id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	UserAgent       string       `json:"user_agent"`
+	IpAddress       string       `json:"ip_address"`
+	AuthenticatedAt time.Time    `json:"authenticated_at"`
+	RotatedAt       sql.NullTime `json:"rotated_at"`
+	RevokedAt       sql.NullTime `json:"revoked_at"`
+	ExpiresAt       time.Time    `json:"expires_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
 type Token struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Token     string    `json:"token"`
-	Type      TokenType `json:"type"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID      `json:"id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Token     sql.NullString `json:"token"`
+	Type      TokenType      `json:"type"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	CreatedAt time.Time      `json:"created_at"`
+	Target    sql.NullString `json:"target"`
+	TokenHash sql.NullString `json:"token_hash"`
+	Attempts  int32          `json:"attempts"`
+}
+
+type UserIdentity struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type UserMfa struct {
+	UserID       uuid.UUID    `json:"user_id"`
+	TotpSecret   string       `json:"totp_secret"`
+	EnabledAt    sql.NullTime `json:"enabled_at"`
+	LastUsedStep int64        `json:"last_used_step"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type WebauthnChallenge struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	Session   json.RawMessage `json:"session"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type WebauthnCredential struct {
+	ID              uuid.UUID    `json:"id"`
+	UserID          uuid.UUID    `json:"user_id"`
+	CredentialID    []byte       `json:"credential_id"`
+	PublicKey       []byte       `json:"public_key"`
+	AttestationType string       `json:"attestation_type"`
+	Transports      string       `json:"transports"`
+	Aaguid          []byte       `json:"aaguid"`
+	SignCount       int64        `json:"sign_count"`
+	Flags           int16        `json:"flags"`
+	Name            string       `json:"name"`
+	LastUsedAt      sql.NullTime `json:"last_used_at"`
+	CreatedAt       time.Time    `json:"created_at"`
 }
@@ -7,35 +7,41 @@ package authServices
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createToken = `-- name: CreateToken :one
-INSERT INTO tokens (user_id, token, type, expires_at)
-VALUES ($1, $2, $3, $4)
+INSERT INTO tokens (user_id, token_hash, type, expires_at, target)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
+    created_at,
+    target,
+    token_hash,
+    attempts
 `
 
 type CreateTokenParams struct {
-	UserID    uuid.UUID `json:"user_id"`
-	Token     string    `json:"token"`
-	Type      TokenType `json:"type"`
-	ExpiresAt time.Time `json:"expires_at"`
+	UserID    uuid.UUID      `json:"user_id"`
+	TokenHash sql.NullString `json:"token_hash"`
+	Type      TokenType      `json:"type"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	Target    sql.NullString `json:"target"`
 }
 
 func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (Token, error) {
 	row := q.db.QueryRowContext(ctx, createToken,
 		arg.UserID,
-		arg.Token,
+		arg.TokenHash,
 		arg.Type,
 		arg.ExpiresAt,
+		arg.Target,
 	)
 	var i Token
 	err := row.Scan(
@@ -45,6 +51,9 @@ func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) (Token
 		&i.Type,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Target,
+		&i.TokenHash,
+		&i.Attempts,
 	)
 	return i, err
 }
@@ -59,28 +68,39 @@ func (q *Queries) DeleteToken(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const getToken = `-- name: GetToken :one
+const deleteUserTokens = `-- name: DeleteUserTokens :exec
+DELETE FROM tokens
+WHERE user_id = $1
+    AND type = $2
+`
+
+type DeleteUserTokensParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Type   TokenType `json:"type"`
+}
+
+func (q *Queries) DeleteUserTokens(ctx context.Context, arg DeleteUserTokensParams) error {
+	_, err := q.db.ExecContext(ctx, deleteUserTokens, arg.UserID, arg.Type)
+	return err
+}
+
+const getTokenByHash = `-- name: GetTokenByHash :one
 SELECT id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
+    created_at,
+    target,
+    token_hash,
+    attempts
 FROM tokens
-WHERE token = $1
-    AND type = $2
-    AND user_id = $3
+WHERE token_hash = $1
 LIMIT 1
 `
 
-type GetTokenParams struct {
-	Token  string    `json:"token"`
-	Type   TokenType `json:"type"`
-	UserID uuid.UUID `json:"user_id"`
-}
-
-func (q *Queries) GetToken(ctx context.Context, arg GetTokenParams) (Token, error) {
-	row := q.db.QueryRowContext(ctx, getToken, arg.Token, arg.Type, arg.UserID)
+func (q *Queries) GetTokenByHash(ctx context.Context, tokenHash sql.NullString) (Token, error) {
+	row := q.db.QueryRowContext(ctx, getTokenByHash, tokenHash)
 	var i Token
 	err := row.Scan(
 		&i.ID,
@@ -89,23 +109,36 @@ func (q *Queries) GetToken(ctx context.Context, arg GetTokenParams) (Token, erro
 		&i.Type,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Target,
+		&i.TokenHash,
+		&i.Attempts,
 	)
 	return i, err
 }
 
-const getTokenByCode = `-- name: GetTokenByCode :one
+const getUserToken = `-- name: GetUserToken :one
 SELECT id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
+    created_at,
+    target,
+    token_hash,
+    attempts
 FROM tokens
-WHERE token = $1
+WHERE user_id = $1
+    AND type = $2
+LIMIT 1
 `
 
-func (q *Queries) GetTokenByCode(ctx context.Context, token string) (Token, error) {
-	row := q.db.QueryRowContext(ctx, getTokenByCode, token)
+type GetUserTokenParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Type   TokenType `json:"type"`
+}
+
+func (q *Queries) GetUserToken(ctx context.Context, arg GetUserTokenParams) (Token, error) {
+	row := q.db.QueryRowContext(ctx, getUserToken, arg.UserID, arg.Type)
 	var i Token
 	err := row.Scan(
 		&i.ID,
@@ -114,23 +147,38 @@ func (q *Queries) GetTokenByCode(ctx context.Context, token string) (Token, erro
 		&i.Type,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Target,
+		&i.TokenHash,
+		&i.Attempts,
 	)
 	return i, err
 }
 
-const getTokenByUserId = `-- name: GetTokenByUserId :one
-SELECT id,
+const incrementTokenAttempts = `-- name: IncrementTokenAttempts :one
+UPDATE tokens
+SET attempts = attempts + 1
+WHERE user_id = $1
+    AND type = $2
+    AND attempts < $3
+RETURNING id,
     user_id,
     token,
     type,
     expires_at,
-    created_at
-FROM tokens
-WHERE user_id = $1
+    created_at,
+    target,
+    token_hash,
+    attempts
 `
 
-func (q *Queries) GetTokenByUserId(ctx context.Context, userID uuid.UUID) (Token, error) {
-	row := q.db.QueryRowContext(ctx, getTokenByUserId, userID)
+type IncrementTokenAttemptsParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Type        TokenType `json:"type"`
+	MaxAttempts int32     `json:"max_attempts"`
+}
+
+func (q *Queries) IncrementTokenAttempts(ctx context.Context, arg IncrementTokenAttemptsParams) (Token, error) {
+	row := q.db.QueryRowContext(ctx, incrementTokenAttempts, arg.UserID, arg.Type, arg.MaxAttempts)
 	var i Token
 	err := row.Scan(
 		&i.ID,
@@ -139,6 +187,71 @@ func (q *Queries) GetTokenByUserId(ctx context.Context, userID uuid.UUID) (Token
 		&i.Type,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.Target,
+		&i.TokenHash,
+		&i.Attempts,
 	)
 	return i, err
 }
+
+const listUnhashedTokens = `-- name: ListUnhashedTokens :many
+SELECT id,
+    user_id,
+    type,
+    token
+FROM tokens
+WHERE token_hash IS NULL
+    AND token IS NOT NULL
+`
+
+type ListUnhashedTokensRow struct {
+	ID     uuid.UUID      `json:"id"`
+	UserID uuid.UUID      `json:"user_id"`
+	Type   TokenType      `json:"type"`
+	Token  sql.NullString `json:"token"`
+}
+
+func (q *Queries) ListUnhashedTokens(ctx context.Context) ([]ListUnhashedTokensRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnhashedTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnhashedTokensRow
+	for rows.Next() {
+		var i ListUnhashedTokensRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Type,
+			&i.Token,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setTokenHash = `-- name: SetTokenHash :exec
+UPDATE tokens
+SET token_hash = $2,
+    token = NULL
+WHERE id = $1
+`
+
+type SetTokenHashParams struct {
+	ID        uuid.UUID      `json:"id"`
+	TokenHash sql.NullString `json:"token_hash"`
+}
+
+func (q *Queries) SetTokenHash(ctx context.Context, arg SetTokenHashParams) error {
+	_, err := q.db.ExecContext(ctx, setTokenHash, arg.ID, arg.TokenHash)
+	return err
+}
@@ -0,0 +1,64 @@
+package authServices
+
+import (
+	"context"
+	"crypto/hmac"
+	"database/sql"
+	"time"
+	"varaden/server/config"
+	"varaden/server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// IsOTP reports whether tokens of type t are short codes that users type in.
+// They are checked against the user's own token, while link tokens are long
+// random strings found by their hash alone.
+func (t TokenType) IsOTP() bool {
+	switch t {
+	case TokenTypeEmailVerify, TokenTypePhoneVerify, TokenTypeLoginStepUp:
+		return true
+	}
+	return false
+}
+
+// HashToken returns the keyed hash stored for a user's token. A 6-digit code
+// is likely to be held by two users at once, so the hash of a one-time code
+// also covers its user and type; see HashLinkToken for link tokens.
+func HashToken(userID uuid.UUID, typ TokenType, code string) sql.NullString {
+	if !typ.IsOTP() {
+		return HashLinkToken(code)
+	}
+	return sql.NullString{String: utils.HashToken(config.EncryptionKey, userID.String()+":"+string(typ)+":"+code), Valid: true}
+}
+
+// HashLinkToken returns the keyed hash of a link token, to look it up by
+// before its user is known.
+func HashLinkToken(code string) sql.NullString {
+	return sql.NullString{String: utils.HashToken(config.EncryptionKey, code), Valid: true}
+}
+
+// CheckToken reports in constant time whether code is the token's code.
+func CheckToken(token Token, code string) bool {
+	return hmac.Equal([]byte(HashToken(token.UserID, token.Type, code).String), []byte(token.TokenHash.String))
+}
+
+// IssueToken replaces the user's token of the given type with code. Only a
+// keyed hash of the code is stored; the code itself is sent to the user.
+func (q *Queries) IssueToken(ctx context.Context, userID uuid.UUID, typ TokenType, code string, expiresAt time.Time, target sql.NullString) error {
+	if err := q.DeleteUserTokens(ctx, DeleteUserTokensParams{
+		UserID: userID,
+		Type:   typ,
+	}); err != nil {
+		return err
+	}
+
+	_, err := q.CreateToken(ctx, CreateTokenParams{
+		UserID:    userID,
+		TokenHash: HashToken(userID, typ, code),
+		Type:      typ,
+		ExpiresAt: expiresAt,
+		Target:    target,
+	})
+	return err
+}
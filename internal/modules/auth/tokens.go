@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/google/uuid"
+)
+
+// maxTokenAttempts is how many codes can be tried against a one-time code.
+const maxTokenAttempts = 5
+
+// getLinkToken looks up the token of an emailed link. Link tokens are long
+// random strings, so they are found by their hash rather than guessed at.
+func (am *AuthModule) getLinkToken(ctx context.Context, code string) (authServices.Token, error) {
+	return am.queries.GetTokenByHash(ctx, authServices.HashLinkToken(code))
+}
+
+// checkOTP compares a one-time code with the user's token of the given type in
+// constant time. Every attempt is counted before the code is compared, in one
+// statement, so parallel guesses cannot get past maxTokenAttempts; the token is
+// deleted once they are used up. ok is false when there is no token, no
+// attempt left or the code is wrong; expiry is left to the caller.
+func (am *AuthModule) checkOTP(ctx context.Context, userID uuid.UUID, typ authServices.TokenType, code string) (token authServices.Token, ok bool, err error) {
+	token, err = am.queries.IncrementTokenAttempts(ctx, authServices.IncrementTokenAttemptsParams{
+		UserID:      userID,
+		Type:        typ,
+		MaxAttempts: maxTokenAttempts,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return authServices.Token{}, false, nil
+	}
+	if err != nil {
+		return authServices.Token{}, false, err
+	}
+
+	if authServices.CheckToken(token, code) {
+		return token, true, nil
+	}
+
+	if token.Attempts >= maxTokenAttempts {
+		if err := am.queries.DeleteToken(ctx, token.ID); err != nil {
+			return authServices.Token{}, false, err
+		}
+	}
+
+	return authServices.Token{}, false, nil
+}
@@ -1,8 +1,21 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 	"varaden/server/config"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func (am *AuthModule) SendVerificationEmail(to, token string) error {
@@ -32,3 +45,250 @@ If you did not request any password resets, then ignore this email.
 `, resetPasswordURL)
 	return am.email.SendEmail(to, subject, body)
 }
+
+func (am *AuthModule) SendMagicLinkEmail(to, token string) error {
+	subject := "Your sign-in link"
+
+	magicLinkURL := fmt.Sprintf("%s/magic-link?token=%s", config.FrontEndURL, token)
+	body := fmt.Sprintf(`
+Dear user,
+
+To sign in, click on this link: %s
+
+The link expires in %d minutes and can be used once. If you did not request it, then ignore this email.
+`, magicLinkURL, int(magicLinkExpiry.Minutes()))
+	return am.email.SendEmail(to, subject, body)
+}
+
+func (am *AuthModule) SendEmailChangeEmail(to, token string) error {
+	subject := "Confirm your new email address"
+
+	confirmURL := fmt.Sprintf("%s/confirm-email-change?token=%s", config.FrontEndURL, token)
+	body := fmt.Sprintf(`
+Dear user,
+
+To use this address for your account, click on this link: %s
+
+The link expires in %d minutes. If you did not request this change, then ignore this email.
+`, confirmURL, int(emailChangeExpiry.Minutes()))
+	return am.email.SendEmail(to, subject, body)
+}
+
+func (am *AuthModule) SendEmailChangedEmail(to, newEmail, undoToken string) error {
+	subject := "Your email address was changed"
+
+	undoURL := fmt.Sprintf("%s/undo-email-change?token=%s", config.FrontEndURL, undoToken)
+	body := fmt.Sprintf(`
+Dear user,
+
+The email address of your account was changed to %s.
+
+If you did not make this change, click on this link within %d days to restore this address and sign out every device: %s
+`, newEmail, int(emailChangeUndoExpiry.Hours()/24), undoURL)
+	return am.email.SendEmail(to, subject, body)
+}
+
+func (am *AuthModule) SendInvitationEmail(to string, inviter *middlewares.Principal, invitation authServices.Invitation) error {
+	subject := "You are invited"
+
+	from := inviter.Name
+	if from == "" {
+		from = inviter.Email
+	}
+	registerURL := fmt.Sprintf("%s/register?invite=%s", config.FrontEndURL, invitation.Code)
+	body := fmt.Sprintf(`
+Dear user,
+
+%s invited you to create an account. To register, click on this link: %s
+
+Or enter this invitation code when registering: %s
+
+The invitation expires on %s. If you do not know the sender, then ignore this email.
+`, from, registerURL, invitation.Code, invitation.ExpiresAt.Time.Format("2 January 2006"))
+	return am.email.SendEmail(to, subject, body)
+}
+
+func (am *AuthModule) SendAccountLockedEmail(to string, lockedUntil time.Time, unlockToken string) error {
+	subject := "Your account was locked"
+
+	unlockURL := fmt.Sprintf("%s/unlock-account?token=%s", config.FrontEndURL, unlockToken)
+	body := fmt.Sprintf(`
+Dear user,
+
+Your account was locked until %s after too many failed sign-in attempts.
+
+If this was you, wait until then or click on this link to unlock your account now and choose a new password: %s
+
+If it was not you, someone may be trying to guess your password. Use the link to choose a new one.
+`, lockedUntil.UTC().Format("2006-01-02 15:04 MST"), unlockURL)
+	return am.email.SendEmail(to, subject, body)
+}
+
+func (am *AuthModule) SendLoginCodeEmail(to, code string) error {
+	subject := "Confirm your sign-in"
+
+	body := fmt.Sprintf(`
+Dear user,
+
+We noticed a sign-in to your account from a new device or location. To finish signing in, enter this code: %s
+
+The code expires in %d minutes. If you did not try to sign in, then change your password.
+`, code, int(stepUpExpiry.Minutes()))
+	return am.email.SendEmail(to, subject, body)
+}
+
+func (am *AuthModule) SendNewDeviceEmail(to string, device authServices.KnownDevice, signals []string, reportToken string) error {
+	subject := "New sign-in to your account"
+
+	location := device.Location
+	if location == "" {
+		location = "Unknown"
+	}
+	var travelNote string
+	if slices.Contains(signals, signalImpossibleTravel) {
+		travelNote = "\nThis sign-in was too far from your previous one for anyone to have travelled between them in time.\n"
+	}
+
+	reportURL := fmt.Sprintf("%s/report-login?token=%s", config.FrontEndURL, reportToken)
+	body := fmt.Sprintf(`
+Dear user,
+
+Your account was signed in to from a device or location it has not used before:
+
+Device: %s
+Approximate location: %s
+IP address: %s
+Time: %s
+%s
+If this was you, then ignore this email. If it was not, click on this link within %d days to sign out every device, then change your password: %s
+`, describeDevice(device.UserAgent), location, device.IpAddress, device.LastSeenAt.UTC().Format("2006-01-02 15:04 MST"), travelNote, int(loginAlertExpiry.Hours()/24), reportURL)
+	return am.email.SendEmail(to, subject, body)
+}
+
+// startSession opens a new refresh token family for the user, issues a token
+// pair and sets the refresh token cookie.
+func (am *AuthModule) startSession(ctx context.Context, c *fiber.Ctx, userID uuid.UUID) (utils.TokenPair, error) {
+	return am.issueSessionTokens(ctx, c, am.queries, userID, uuid.New(), time.Now())
+}
+
+// issueSessionTokens records a refresh token in the given family, signs the
+// token pair for it and sets the refresh token cookie.
+func (am *AuthModule) issueSessionTokens(ctx context.Context, c *fiber.Ctx, queries *authServices.Queries, userID, familyID uuid.UUID, authenticatedAt time.Time) (utils.TokenPair, error) {
+	session, err := queries.CreateSession(ctx, authServices.CreateSessionParams{
+		FamilyID:        familyID,
+		UserID:          userID,
+		UserAgent:       utils.Truncate(c.Get(fiber.HeaderUserAgent), 512),
+		IpAddress:       c.IP(),
+		AuthenticatedAt: authenticatedAt,
+		ExpiresAt:       time.Now().Add(time.Duration(am.jwt.RefreshExpiry) * 24 * time.Hour),
+	})
+	if err != nil {
+		return utils.TokenPair{}, err
+	}
+
+	tokens, err := am.jwt.GenerateToken(userID, session.ID, session.FamilyID)
+	if err != nil {
+		return utils.TokenPair{}, err
+	}
+
+	// Set refresh token in HTTP-only cookie
+	am.jwt.SetRefreshCookie(c, tokens.RefreshToken)
+
+	return tokens, nil
+}
+
+// rehashPassword stores a new hash of the user's current password. It keeps
+// password_changed_at, so the user's other sessions stay signed in.
+func (am *AuthModule) rehashPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := am.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := am.user.WithTx(tx)
+
+	if err := qtx.MarkPasswordRehash(ctx); err != nil {
+		return err
+	}
+	if err := qtx.UpdatePassword(ctx, userServices.UpdatePasswordParams{
+		PasswordHash: passwordHash,
+		ID:           userID,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recentlySignedIn reports whether the principal's session was signed in to
+// within the given time. Requests made with an API key have no session, so
+// they never count as recent.
+func (am *AuthModule) recentlySignedIn(ctx context.Context, principal *middlewares.Principal, within time.Duration) (bool, error) {
+	if principal.SessionID == uuid.Nil {
+		return false, nil
+	}
+
+	authenticatedAt, err := am.queries.GetSessionFamilyAuthenticatedAt(ctx, authServices.GetSessionFamilyAuthenticatedAtParams{
+		FamilyID: principal.SessionID,
+		UserID:   principal.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return time.Since(authenticatedAt) < within, nil
+}
+
+// revokeRefreshSession revokes the token family of the refresh token in the
+// request cookie, if it is valid.
+func (am *AuthModule) revokeRefreshSession(ctx context.Context, c *fiber.Ctx) error {
+	claims, err := am.jwt.RefreshTokenValidate(c.Cookies(am.jwt.RefreshCookieName))
+	if err != nil {
+		return nil
+	}
+
+	familyID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil
+	}
+	if err := am.queries.RevokeSessionFamily(ctx, familyID); err != nil {
+		return err
+	}
+
+	userID, _ := uuid.Parse(claims.Subject)
+	am.logEvent(ctx, c, eventLogout, userID, "")
+	return nil
+}
+
+// apiKeyResponse describes an API key without its hash.
+func apiKeyResponse(apiKey authServices.ApiKey) fiber.Map {
+	scopes := []string{}
+	if apiKey.Scopes != "" {
+		scopes = strings.Split(apiKey.Scopes, ",")
+	}
+	var expiresAt, lastUsedAt *time.Time
+	if apiKey.ExpiresAt.Valid {
+		expiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.LastUsedAt.Valid {
+		lastUsedAt = &apiKey.LastUsedAt.Time
+	}
+
+	return fiber.Map{
+		"id":           apiKey.ID,
+		"name":         apiKey.Name,
+		"prefix":       apiKey.Prefix,
+		"scopes":       scopes,
+		"expires_at":   expiresAt,
+		"last_used_at": lastUsedAt,
+		"created_at":   apiKey.CreatedAt,
+	}
+}
@@ -1,19 +1,54 @@
 package auth
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
 
+	"github.com/google/uuid"
+)
+
+// Passwords being set are checked against utils.PasswordRules in the handler,
+// where the user's email is known.
 type registerData struct {
 	Email    string `json:"email" validate:"required,email,max=250" example:"user@example.com"`
-	Password string `json:"password" validate:"required,min=8,max=100,password" example:"password1"`
+	Password string `json:"password" validate:"required,max=100" example:"password1"`
+	// InviteCode is required when registration is invite-only
+	InviteCode string `json:"invite_code" validate:"omitempty,max=32" example:"q8Zr2xKpL4mN"`
+}
+
+// loginData does not apply the password policy, so tightening it never locks
+// out users whose passwords predate it.
+type loginData struct {
+	Email    string `json:"email" validate:"required,email,max=250" example:"user@example.com"`
+	Password string `json:"password" validate:"required,max=100" example:"password1"`
 }
 
 type forgotPasswordData struct {
 	Email string `json:"email" validate:"required,email,max=250" example:"user@example.com"`
 }
 
+type magicLinkData struct {
+	Email string `json:"email" validate:"required,email,max=250" example:"user@example.com"`
+}
+
+type magicLinkLoginData struct {
+	Token string `json:"token" validate:"required,len=32" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"`
+}
+
+type changePasswordData struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=100" example:"password1"`
+	Password        string `json:"new_password" validate:"required,max=100" example:"password2"`
+	ConfirmPassword string `json:"confirm_password" validate:"required,max=100" example:"password2"`
+}
+
 type resetPasswordData struct {
-	Password        string `json:"new_password" validate:"required,min=8,max=100,password" example:"password1"`
-	ConfirmPassword string `json:"confirm_password" validate:"required,min=8,max=100,password" example:"password1"`
+	Password        string `json:"new_password" validate:"required,max=100" example:"password1"`
+	ConfirmPassword string `json:"confirm_password" validate:"required,max=100" example:"password1"`
+	Token           string `json:"token" validate:"required,len=32" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"`
+}
+
+type unlockAccountData struct {
+	Password        string `json:"new_password" validate:"required,max=100" example:"password1"`
+	ConfirmPassword string `json:"confirm_password" validate:"required,max=100" example:"password1"`
 	Token           string `json:"token" validate:"required,len=32" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"`
 }
 
@@ -29,3 +64,117 @@ type verifyEmailData struct {
 type refreshTokensData struct {
 	Logout bool `json:"logout" example:"true"`
 }
+
+type mfaVerifyData struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,number" example:"123456"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=20" example:"abcde-fghij"`
+}
+
+type stepUpVerifyData struct {
+	StepUpToken string `json:"step_up_token" validate:"required"`
+	Code        string `json:"code" validate:"required,len=6,number" example:"123456"`
+}
+
+type reportLoginData struct {
+	Token string `json:"token" validate:"required,len=32" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"`
+}
+
+type invitationData struct {
+	Email         string `json:"email" validate:"omitempty,email,max=250" example:"friend@example.com"`
+	ExpiresInDays int    `json:"expires_in_days" validate:"omitempty,min=1,max=90" example:"14"`
+}
+
+// adminInvitationData lets staff invite a group, such as a whole city, with
+// one code.
+type adminInvitationData struct {
+	Email         string `json:"email" validate:"omitempty,email,max=250" example:"friend@example.com"`
+	MaxUses       int32  `json:"max_uses" validate:"omitempty,min=1,max=100000" example:"500"`
+	ExpiresInDays int    `json:"expires_in_days" validate:"omitempty,min=1,max=365" example:"30"`
+}
+
+type invitationsQuery struct {
+	Before   string `query:"before" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2026-10-17T00:00:00Z"`
+	BeforeID string `query:"before_id" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Limit    int32  `query:"limit" validate:"omitempty,min=1,max=100" example:"50"`
+}
+
+type adminInvitationsQuery struct {
+	InviterID string `query:"inviter_id" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Before    string `query:"before" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2026-10-17T00:00:00Z"`
+	BeforeID  string `query:"before_id" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Limit     int32  `query:"limit" validate:"omitempty,min=1,max=100" example:"50"`
+}
+
+type restoreAccountData struct {
+	Token string `json:"token" validate:"required,len=32" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"`
+}
+
+type totpCodeData struct {
+	Code string `json:"code" validate:"required,len=6,number" example:"123456"`
+}
+
+type passwordConfirmData struct {
+	Password string `json:"password" validate:"required,max=100" example:"password1"`
+}
+
+type passkeyRegisterData struct {
+	CeremonyToken string          `json:"ceremony_token" validate:"required"`
+	Name          string          `json:"name" validate:"max=100" example:"MacBook"`
+	Credential    json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
+
+type passkeyLoginData struct {
+	CeremonyToken string          `json:"ceremony_token" validate:"required"`
+	Credential    json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
+
+type phoneData struct {
+	Phone string `json:"phone" validate:"required,max=20" example:"01712345678"`
+}
+
+type phoneVerifyData struct {
+	OTP string `json:"otp" validate:"required,len=6,number" example:"123456"`
+}
+
+type phoneLoginData struct {
+	Phone string `json:"phone" validate:"required,max=20" example:"01712345678"`
+	OTP   string `json:"otp" validate:"required,len=6,number" example:"123456"`
+}
+
+type apiKeyData struct {
+	Name          string   `json:"name" validate:"required,max=100" example:"Listing import"`
+	Scopes        []string `json:"scopes" validate:"max=50,dive,required,max=100" example:"users:read"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365" example:"90"`
+}
+
+type changeEmailData struct {
+	Email string `json:"new_email" validate:"required,email,max=250" example:"new@example.com"`
+	// Password is required for accounts that have one
+	Password string `json:"password" validate:"omitempty,max=100" example:"password1"`
+}
+
+type emailChangeTokenData struct {
+	Token string `json:"token" validate:"required,len=32" example:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6"`
+}
+
+type impersonateData struct {
+	Reason string `json:"reason" validate:"required,max=255" example:"Ticket 1234: listing photos do not upload"`
+}
+
+type activityQuery struct {
+	BeforeID int64 `query:"before_id" validate:"omitempty,min=1" example:"1024"`
+	Limit    int32 `query:"limit" validate:"omitempty,min=1,max=100" example:"50"`
+}
+
+type authEventsQuery struct {
+	UserID    string `query:"user_id" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ActorID   string `query:"actor_id" validate:"omitempty,uuid" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Event     string `query:"event" validate:"omitempty,max=50" example:"login"`
+	Outcome   string `query:"outcome" validate:"omitempty,oneof=success failure" example:"failure"`
+	IPAddress string `query:"ip_address" validate:"omitempty,ip" example:"203.0.113.7"`
+	Since     string `query:"since" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2026-10-01T00:00:00Z"`
+	Until     string `query:"until" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2026-10-17T00:00:00Z"`
+	BeforeID  int64  `query:"before_id" validate:"omitempty,min=1" example:"1024"`
+	Limit     int32  `query:"limit" validate:"omitempty,min=1,max=200" example:"50"`
+}
@@ -3,21 +3,53 @@ package modules
 import (
 	"database/sql"
 	"varaden/server/config"
+	"varaden/server/internal/middlewares"
 	"varaden/server/internal/modules/auth"
 	healthCheck "varaden/server/internal/modules/health_check"
+	"varaden/server/internal/modules/rbac"
 	"varaden/server/internal/modules/user"
 	"varaden/server/internal/services"
 	"varaden/server/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 )
 
 func Setup(app *fiber.App, db *sql.DB, config config.AllConfig) {
 	v1Group := app.Group("/api/v1")
+	// Modules add their admin routes to one group, each behind adminOnly
+	admin := v1Group.Group("/admin")
+	adminOnly := middlewares.Admin(db)
 	emailService := services.NewEmailService(&config.SMTP)
+	smsService, err := services.NewSMSService(&config.SMS)
+	if err != nil {
+		log.Fatalf("SMS: %v", err)
+	}
+	rateLimitStore := services.NewRateLimitStore(&config.RateLimit, auth.NewRateLimitCounters(db))
+	geoIPService, err := services.NewGeoIPService(&config.LoginRisk)
+	if err != nil {
+		log.Fatalf("GeoIP database: %v", err)
+	}
 
-	user.RegisterUserModule(v1Group, db).SetupRoutes()
-	auth.RegisterAuthModule(v1Group, db, emailService).SetupRoutes()
+	user.RegisterUserModule(v1Group, db, emailService, rateLimitStore, config.RateLimit, config.Account).SetupRoutes()
+	authModule := auth.RegisterAuthModule(v1Group, auth.Deps{
+		DB:           db,
+		Admin:        admin,
+		AdminOnly:    adminOnly,
+		Email:        emailService,
+		SMS:          smsService,
+		RateLimits:   rateLimitStore,
+		GeoIP:        geoIPService,
+		Google:       config.Google,
+		RateLimit:    config.RateLimit,
+		Lockout:      config.Lockout,
+		LoginRisk:    config.LoginRisk,
+		CORS:         config.CORS,
+		Registration: config.Registration,
+	})
+	authModule.SetupRoutes()
+	authModule.SetupWellKnownRoutes(app)
+	rbac.RegisterRbacModule(admin, adminOnly, db).SetupRoutes()
 	healthCheck.RegisterHealthCheckModule(v1Group, db).SetupRoutes()
 
 	// 404 Handler
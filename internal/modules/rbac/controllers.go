@@ -0,0 +1,242 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"varaden/server/internal/middlewares"
+	rbacServices "varaden/server/internal/modules/rbac/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// List roles
+//
+//	@Summary		List roles
+//	@Description	Lists every role with the permissions it grants. Requires the roles:read permission.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Success		200	{object}	utils.GenericResponse	"Roles and their permissions"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Failure		403	{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Router			/admin/roles [get]
+func (rm *RbacModule) listRoles(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	roles, err := rm.rbac.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := rm.rbac.ListRolePermissions(ctx)
+	if err != nil {
+		return err
+	}
+	permissions := make(map[string][]string)
+	for _, row := range rows {
+		permissions[row.Role] = append(permissions[row.Role], row.Permission)
+	}
+
+	data := make([]fiber.Map, 0, len(roles))
+	for _, role := range roles {
+		rolePermissions := permissions[role.Name]
+		if rolePermissions == nil {
+			rolePermissions = []string{}
+		}
+		data = append(data, fiber.Map{
+			"name":        role.Name,
+			"description": role.Description,
+			"is_default":  role.IsDefault,
+			"permissions": rolePermissions,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// List a user's roles
+//
+//	@Summary		List user roles
+//	@Description	Lists the roles granted to a user. Requires the roles:read permission.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			id	path		string					true	"User ID"
+//	@Success		200	{object}	utils.GenericResponse	"Roles of the user"
+//	@Failure		403	{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Failure		404	{object}	utils.CommonError		"User not found"
+//	@Router			/admin/users/{id}/roles [get]
+func (rm *RbacModule) listUserRoles(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, err := rm.getTargetUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	roles, err := rm.rbac.ListUserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"id":    userID,
+			"roles": roles,
+		},
+	})
+}
+
+// Grant a role
+//
+//	@Summary		Grant role
+//	@Description	Grants a role to a user. Granting a role the user already has is a no-op. Requires the roles:write permission.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			id		path		string					true	"User ID"
+//	@Param			request	body		grantRoleData			true	"Role to grant"
+//	@Success		200		{object}	utils.GenericResponse	"Role granted"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Failure		404		{object}	utils.CommonError		"User or role not found"
+//	@Router			/admin/users/{id}/roles [post]
+func (rm *RbacModule) grantRole(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(grantRoleData)
+
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := rm.validate.Struct(req); err != nil {
+		return err
+	}
+
+	userID, err := rm.getTargetUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	role, err := rm.rbac.GetRoleByName(ctx, req.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, "Role not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := rm.rbac.GrantUserRole(ctx, rbacServices.GrantUserRoleParams{
+		UserID:    userID,
+		RoleID:    role.ID,
+		GrantedBy: uuid.NullUUID{UUID: principal.ID, Valid: true},
+	}); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Role granted successfully",
+		},
+	})
+}
+
+// Revoke a role
+//
+//	@Summary		Revoke role
+//	@Description	Revokes a role from a user. The last admin cannot lose the admin role. Requires the roles:write permission.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Param			id		path		string					true	"User ID"
+//	@Param			role	path		string					true	"Role name"
+//	@Success		200		{object}	utils.GenericResponse	"Role revoked"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: Missing permission"
+//	@Failure		404		{object}	utils.CommonError		"User does not have the role"
+//	@Failure		409		{object}	utils.CommonError		"Cannot revoke the last admin"
+//	@Router			/admin/users/{id}/roles/{role} [delete]
+func (rm *RbacModule) revokeRole(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, err := rm.getTargetUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	role, err := rm.rbac.GetRoleByName(ctx, c.Params("role"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, "Role not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := rm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := rm.rbac.WithTx(tx)
+
+	// Lock the admins first, so concurrent revocations cannot each count the
+	// other's admin as still there
+	if role.Name == adminRole {
+		if err := qtx.LockRoleUsers(ctx, role.ID); err != nil {
+			return err
+		}
+	}
+
+	revoked, err := qtx.RevokeUserRole(ctx, rbacServices.RevokeUserRoleParams{
+		UserID: userID,
+		RoleID: role.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "User does not have this role")
+	}
+
+	// Someone has to be able to manage roles
+	if role.Name == adminRole {
+		admins, err := qtx.CountRoleUsers(ctx, role.ID)
+		if err != nil {
+			return err
+		}
+		if admins == 0 {
+			return fiber.NewError(fiber.StatusConflict, "Cannot revoke the admin role from the last admin")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message": "Role revoked successfully",
+		},
+	})
+}
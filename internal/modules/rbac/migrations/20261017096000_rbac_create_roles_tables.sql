@@ -0,0 +1,83 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE roles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    -- Granted to every new user
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE permissions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- <resource>:<action>, e.g. users:read
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+CREATE TABLE role_permissions (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id UUID NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    granted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+-- Indexes for performance
+CREATE INDEX user_roles_role_id ON user_roles (role_id);
+-- Default role set
+INSERT INTO roles (name, description, is_default)
+VALUES ('admin', 'Full access, including managing roles', FALSE),
+    ('support', 'Support staff: look up users and sign them out', FALSE),
+    ('landlord', 'Lists and manages properties', FALSE),
+    ('tenant', 'Rents properties', TRUE);
+INSERT INTO permissions (name, description)
+VALUES ('users:read', 'View user accounts'),
+    ('users:write', 'Create and change user accounts'),
+    ('roles:read', 'View roles and role assignments'),
+    ('roles:write', 'Grant and revoke roles'),
+    ('sessions:revoke', 'Sign users out of all sessions');
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id,
+    p.id
+FROM roles r
+    CROSS JOIN permissions p
+WHERE r.name = 'admin'
+    OR (
+        r.name = 'support'
+        AND p.name IN ('users:read', 'roles:read', 'sessions:revoke')
+    );
+-- Give existing users the default roles
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id,
+    r.id
+FROM users u
+    CROSS JOIN roles r
+WHERE r.is_default = TRUE;
+-- Trigger to grant the default roles to new users
+CREATE OR REPLACE FUNCTION grant_default_roles() RETURNS TRIGGER AS $$ BEGIN
+INSERT INTO user_roles (user_id, role_id)
+SELECT NEW.id,
+    id
+FROM roles
+WHERE is_default = TRUE;
+RETURN NEW;
+END;
+$$ LANGUAGE 'plpgsql';
+DROP TRIGGER IF EXISTS grant_default_roles ON users;
+CREATE TRIGGER grant_default_roles
+AFTER
+INSERT ON users FOR EACH ROW EXECUTE FUNCTION grant_default_roles();
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS grant_default_roles ON users;
+DROP FUNCTION IF EXISTS grant_default_roles();
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO permissions (name, description)
+VALUES ('audit:read', 'View the authentication audit log') ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id,
+    p.id
+FROM roles r
+    CROSS JOIN permissions p
+WHERE r.name IN ('admin', 'support')
+    AND p.name = 'audit:read' ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions
+WHERE name = 'audit:read';
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO permissions (name, description)
+VALUES ('invitations:manage', 'Create invitations with several uses and list everyone''s invitations') ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id,
+    p.id
+FROM roles r
+    CROSS JOIN permissions p
+WHERE r.name = 'admin'
+    AND p.name = 'invitations:manage' ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions
+WHERE name = 'invitations:manage';
+-- +goose StatementEnd
\ No newline at end of file
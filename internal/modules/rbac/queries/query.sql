@@ -0,0 +1,57 @@
+-- name: ListUserRoles :many
+SELECT r.name
+FROM user_roles ur
+    JOIN roles r ON r.id = ur.role_id
+WHERE ur.user_id = $1
+ORDER BY r.name;
+-- name: ListUserPermissions :many
+SELECT DISTINCT p.name
+FROM user_roles ur
+    JOIN role_permissions rp ON rp.role_id = ur.role_id
+    JOIN permissions p ON p.id = rp.permission_id
+WHERE ur.user_id = $1
+ORDER BY p.name;
+-- name: ListRoles :many
+SELECT id,
+    name,
+    description,
+    is_default,
+    created_at
+FROM roles
+ORDER BY name;
+-- name: ListRolePermissions :many
+SELECT r.name AS role,
+    p.name AS permission
+FROM role_permissions rp
+    JOIN roles r ON r.id = rp.role_id
+    JOIN permissions p ON p.id = rp.permission_id
+ORDER BY r.name,
+    p.name;
+-- name: GetRoleByName :one
+SELECT id,
+    name,
+    description,
+    is_default,
+    created_at
+FROM roles
+WHERE name = $1
+LIMIT 1;
+-- name: GrantUserRole :execrows
+INSERT INTO user_roles (user_id, role_id, granted_by)
+VALUES ($1, $2, $3) ON CONFLICT (user_id, role_id) DO NOTHING;
+-- name: RevokeUserRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1
+    AND role_id = $2;
+-- name: CountRoleUsers :one
+SELECT COUNT(*)
+FROM user_roles
+WHERE role_id = $1;
+-- name: LockRoleUsers :exec
+SELECT user_id
+FROM user_roles
+WHERE role_id = $1 FOR
+UPDATE;
+-- name: DeleteUserRoles :exec
+DELETE FROM user_roles
+WHERE user_id = $1;
\ No newline at end of file
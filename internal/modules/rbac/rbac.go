@@ -0,0 +1,31 @@
+package rbac
+
+import (
+	"database/sql"
+	rbacServices "varaden/server/internal/modules/rbac/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RbacModule struct {
+	db        *sql.DB
+	admin     fiber.Router
+	adminOnly fiber.Handler
+	validate  *validator.Validate
+	rbac      *rbacServices.Queries
+	user      *userServices.Queries
+}
+
+func RegisterRbacModule(admin fiber.Router, adminOnly fiber.Handler, db *sql.DB) *RbacModule {
+	return &RbacModule{
+		db:        db,
+		admin:     admin,
+		adminOnly: adminOnly,
+		validate:  utils.Validator(),
+		rbac:      rbacServices.New(db),
+		user:      userServices.New(db),
+	}
+}
@@ -0,0 +1,10 @@
+package rbac
+
+import "varaden/server/internal/middlewares"
+
+func (rm *RbacModule) SetupRoutes() {
+	rm.admin.Get("/roles", rm.adminOnly, middlewares.RequirePermission("roles:read"), rm.listRoles)
+	rm.admin.Get("/users/:id/roles", rm.adminOnly, middlewares.RequirePermission("roles:read"), rm.listUserRoles)
+	rm.admin.Post("/users/:id/roles", rm.adminOnly, middlewares.RequirePermission("roles:write"), rm.grantRole)
+	rm.admin.Delete("/users/:id/roles/:role", rm.adminOnly, middlewares.RequirePermission("roles:write"), rm.revokeRole)
+}
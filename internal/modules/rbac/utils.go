@@ -0,0 +1,31 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// adminRole is the role that manages roles; its last holder cannot lose it.
+const adminRole = "admin"
+
+// getTargetUser resolves the :id route parameter to an existing user ID.
+func (rm *RbacModule) getTargetUser(ctx context.Context, c *fiber.Ctx) (uuid.UUID, error) {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	user, err := rm.user.GetUserById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return user.ID, nil
+}
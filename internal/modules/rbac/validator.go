@@ -0,0 +1,5 @@
+package rbac
+
+type grantRoleData struct {
+	Role string `json:"role" validate:"required,max=50" example:"landlord"`
+}
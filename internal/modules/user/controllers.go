@@ -1,6 +1,10 @@
 package user
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"varaden/server/internal/middlewares"
+
+	"github.com/gofiber/fiber/v2"
+)
 
 func (um *UserModule) getAllUsers(c *fiber.Ctx) error {
 	// Placeholder implementation
@@ -15,3 +19,25 @@ func (um *UserModule) createUser(c *fiber.Ctx) error {
 		"message": "Create user - not implemented",
 	})
 }
+
+// Get the current user
+//
+//	@Summary		Get current user
+//	@Description	Returns the profile of the user identified by the bearer access token.
+//	@Tags			Users
+//	@Produce		json
+//	@Security		JWT
+//	@Security		APIKey
+//	@Success		200	{object}	utils.GenericResponse	"Authenticated user"
+//	@Failure		401	{object}	utils.CommonError		"Unauthorized: Missing, invalid or expired token"
+//	@Router			/users/me [get]
+func (um *UserModule) getMe(c *fiber.Ctx) error {
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": principal,
+	})
+}
@@ -0,0 +1,108 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Delete the current user's account
+//
+//	@Summary		Delete account
+//	@Description	Deactivates the current user's account after confirming their password and signs out every device. The account can be restored with the link emailed to the user until the grace period ends, after which its personal data is erased. Users without a password must set one with forgot-password first.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Security		JWT
+//	@Param			request	body		deleteAccountData		true	"Current password"
+//	@Success		200		{object}	utils.GenericResponse	"Account deleted. Contains when its data will be erased."
+//	@Failure		400		{object}	utils.CommonError		"Bad Request: No password set"
+//	@Failure		401		{object}	utils.CommonError		"Password is incorrect"
+//	@Failure		403		{object}	utils.CommonError		"Forbidden: API key or impersonated request"
+//	@Failure		429		{object}	utils.CommonError		"Too many attempts"
+//	@Router			/users/me [delete]
+func (um *UserModule) deleteAccount(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+	if principal.APIKeyID.Valid {
+		return fiber.NewError(fiber.StatusForbidden, "Deleting the account needs a signed-in session")
+	}
+
+	req := new(deleteAccountData)
+	// Parse and validate request
+	if err := c.BodyParser(req); err != nil {
+		return err
+	}
+	if err := um.validate.Struct(req); err != nil {
+		return err
+	}
+
+	user, err := um.user.GetUserById(ctx, principal.ID)
+	if err != nil {
+		return err
+	}
+	// Users who only sign in with Google, passkeys or links have no password
+	// to confirm with
+	if user.PasswordHash == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Set a password with forgot password before deleting your account")
+	}
+	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Password is incorrect")
+	}
+
+	deletionAt := time.Now().UTC().AddDate(0, 0, um.account.DeletionGraceDays)
+	var restoreToken string
+
+	tx, err := um.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := um.auth.WithTx(tx)
+
+	if err := um.user.WithTx(tx).DeleteUser(ctx, userServices.DeleteUserParams{
+		ID:                  user.ID,
+		DeletionScheduledAt: sql.NullTime{Time: deletionAt, Valid: true},
+	}); err != nil {
+		return err
+	}
+	if _, err := qtx.RevokeAllUserSessions(ctx, user.ID); err != nil {
+		return err
+	}
+	if um.account.DeletionGraceDays > 0 {
+		restoreToken = utils.GenerateRandomString(32)
+		if err := qtx.IssueToken(ctx, user.ID, authServices.TokenTypeAccountRestore, restoreToken, deletionAt, sql.NullString{}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	um.logEvent(ctx, c, eventAccountDelete, principal)
+
+	// The account is gone either way, so a lost email only costs the link
+	if err := um.SendAccountDeletedEmail(user.Email, deletionAt, restoreToken); err != nil {
+		log.Errorf("failed to send account deleted email to user %s: %v", user.ID, err)
+	}
+
+	um.jwt.GetExpiredRefreshCookie(c)
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"message":               "Account deleted",
+			"deletion_scheduled_at": deletionAt,
+		},
+	})
+}
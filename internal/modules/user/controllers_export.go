@@ -0,0 +1,294 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// exportPage is how many auth events or invitations are read at a time for
+// an export.
+const exportPage = 500
+
+// Export the current user's data
+//
+//	@Summary		Export data
+//	@Description	Downloads a zip archive of JSON files with everything stored about the current user: profile, roles, sessions, linked identities, MFA status, passkeys, API keys, known devices, invitations sent and received, and account activity. Secrets such as password and key hashes are left out.
+//	@Tags			Users
+//	@Produce		application/zip
+//	@Security		JWT
+//	@Success		200	{file}		file				"Zip archive of JSON files"
+//	@Failure		401	{object}	utils.CommonError	"Unauthorized: Missing, invalid or expired token"
+//	@Failure		403	{object}	utils.CommonError	"Forbidden: API key or impersonated request"
+//	@Router			/users/me/export [get]
+func (um *UserModule) exportData(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	principal, err := middlewares.GetPrincipal(c)
+	if err != nil {
+		return err
+	}
+	if principal.APIKeyID.Valid {
+		return fiber.NewError(fiber.StatusForbidden, "Exporting data needs a signed-in session")
+	}
+
+	files, err := um.collectUserData(ctx, principal)
+	if err != nil {
+		return err
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(file.name + ".json")
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	um.logEvent(ctx, c, eventDataExport, principal)
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="account-data-%s.zip"`, time.Now().UTC().Format("2006-01-02")))
+	return c.Send(archive.Bytes())
+}
+
+// exportFile is one JSON file of a data export.
+type exportFile struct {
+	name string
+	data any
+}
+
+// collectUserData gathers what every module stores about the user, one file
+// per kind of record.
+func (um *UserModule) collectUserData(ctx context.Context, principal *middlewares.Principal) ([]exportFile, error) {
+	profile, err := um.user.GetUserExport(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := um.rbac.ListUserRoles(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := um.auth.ListUserSessions(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := um.auth.ListUserIdentities(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Users who never set up MFA have no row
+	mfa, err := um.auth.GetUserMFA(ctx, principal.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	passkeys, err := um.auth.ListUserWebauthnCredentials(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys, err := um.auth.ListUserAPIKeys(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	devices, err := um.auth.ListKnownDevices(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := um.listAllAuthEvents(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := um.listAllInvitations(ctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Users who registered without an invitation have no row
+	invitedBy, err := um.auth.GetUserInvitationRedemption(ctx, principal.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	invited := err == nil
+
+	return []exportFile{
+		{"profile", fiber.Map{
+			"id":                    profile.ID,
+			"email":                 profile.Email,
+			"name":                  profile.Name,
+			"date_of_birth":         nullTime(profile.DateOfBirth),
+			"phone":                 nullString(profile.Phone),
+			"phone_verified":        profile.PhoneVerified,
+			"verified_email":        profile.VerifiedEmail,
+			"is_active":             profile.IsActive,
+			"onboarded":             profile.Onboarded,
+			"created_at":            profile.CreatedAt,
+			"updated_at":            profile.UpdatedAt,
+			"last_login_at":         nullTime(profile.LastLoginAt),
+			"password_changed_at":   profile.PasswordChangedAt,
+			"deactivated_at":        nullTime(profile.DeactivatedAt),
+			"deletion_scheduled_at": nullTime(profile.DeletionScheduledAt),
+		}},
+		{"roles", nonNil(roles)},
+		{"sessions", exportList(sessions, func(session authServices.Session) fiber.Map {
+			return fiber.Map{
+				"id":               session.ID,
+				"family_id":        session.FamilyID,
+				"user_agent":       session.UserAgent,
+				"ip_address":       session.IpAddress,
+				"authenticated_at": session.AuthenticatedAt,
+				"rotated_at":       nullTime(session.RotatedAt),
+				"revoked_at":       nullTime(session.RevokedAt),
+				"expires_at":       session.ExpiresAt,
+				"created_at":       session.CreatedAt,
+			}
+		})},
+		{"identities", exportList(identities, func(identity authServices.UserIdentity) fiber.Map {
+			return fiber.Map{
+				"provider":  identity.Provider,
+				"subject":   identity.Subject,
+				"email":     identity.Email,
+				"linked_at": identity.LinkedAt,
+			}
+		})},
+		{"mfa", fiber.Map{
+			"enabled":    mfa.EnabledAt.Valid,
+			"enabled_at": nullTime(mfa.EnabledAt),
+		}},
+		{"passkeys", exportList(passkeys, func(passkey authServices.WebauthnCredential) fiber.Map {
+			return fiber.Map{
+				"id":           passkey.ID,
+				"name":         passkey.Name,
+				"transports":   passkey.Transports,
+				"last_used_at": nullTime(passkey.LastUsedAt),
+				"created_at":   passkey.CreatedAt,
+			}
+		})},
+		{"api_keys", exportList(apiKeys, func(apiKey authServices.ApiKey) fiber.Map {
+			return fiber.Map{
+				"id":           apiKey.ID,
+				"name":         apiKey.Name,
+				"prefix":       apiKey.Prefix,
+				"scopes":       apiKey.Scopes,
+				"expires_at":   nullTime(apiKey.ExpiresAt),
+				"last_used_at": nullTime(apiKey.LastUsedAt),
+				"revoked_at":   nullTime(apiKey.RevokedAt),
+				"created_at":   apiKey.CreatedAt,
+			}
+		})},
+		{"known_devices", exportList(devices, func(device authServices.KnownDevice) fiber.Map {
+			return fiber.Map{
+				"id":            device.ID,
+				"user_agent":    device.UserAgent,
+				"ip_address":    device.IpAddress,
+				"network":       device.Network,
+				"country_code":  device.CountryCode,
+				"location":      device.Location,
+				"first_seen_at": device.FirstSeenAt,
+				"last_seen_at":  device.LastSeenAt,
+			}
+		})},
+		{"invitations", exportList(invitations, func(invitation authServices.ListInvitationsRow) fiber.Map {
+			return fiber.Map{
+				"id":         invitation.ID,
+				"code":       invitation.Code,
+				"email":      nullString(invitation.Email),
+				"max_uses":   invitation.MaxUses,
+				"uses":       invitation.Uses,
+				"expires_at": nullTime(invitation.ExpiresAt),
+				"created_at": invitation.CreatedAt,
+				"invitees":   invitation.Invitees,
+			}
+		})},
+		{"invited_by", invitedByExport(invitedBy, invited)},
+		{"activity", exportList(events, func(event authServices.AuthEvent) fiber.Map {
+			return fiber.Map{
+				"id":         event.ID,
+				"event":      event.Event,
+				"outcome":    event.Outcome,
+				"reason":     event.Reason,
+				"ip_address": event.IpAddress,
+				"user_agent": event.UserAgent,
+				"created_at": event.CreatedAt,
+			}
+		})},
+	}, nil
+}
+
+// listAllAuthEvents reads every auth event about the user, newest first.
+func (um *UserModule) listAllAuthEvents(ctx context.Context, userID uuid.UUID) ([]authServices.AuthEvent, error) {
+	var events []authServices.AuthEvent
+	var beforeID sql.NullInt64
+	for {
+		page, err := um.auth.ListUserAuthEvents(ctx, authServices.ListUserAuthEventsParams{
+			UserID:   userID,
+			BeforeID: beforeID,
+			RowLimit: exportPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+		if len(page) < exportPage {
+			return events, nil
+		}
+		beforeID = sql.NullInt64{Int64: page[len(page)-1].ID, Valid: true}
+	}
+}
+
+// listAllInvitations reads every invitation the user created, newest first.
+func (um *UserModule) listAllInvitations(ctx context.Context, userID uuid.UUID) ([]authServices.ListInvitationsRow, error) {
+	var invitations []authServices.ListInvitationsRow
+	var before sql.NullTime
+	var beforeID uuid.NullUUID
+	for {
+		page, err := um.auth.ListInvitations(ctx, authServices.ListInvitationsParams{
+			InviterID: uuid.NullUUID{UUID: userID, Valid: true},
+			Before:    before,
+			BeforeID:  beforeID,
+			RowLimit:  exportPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, page...)
+		if len(page) < exportPage {
+			return invitations, nil
+		}
+		last := page[len(page)-1]
+		before = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		beforeID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+}
+
+// invitedByExport describes the invitation the user registered with, or is
+// null when there was none.
+func invitedByExport(redemption authServices.GetUserInvitationRedemptionRow, found bool) any {
+	if !found {
+		return nil
+	}
+	return fiber.Map{
+		"invitation_id": redemption.InvitationID,
+		"inviter_id":    redemption.InviterID,
+		"redeemed_at":   redemption.RedeemedAt,
+	}
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+-- A verified phone number signs in one account only
+CREATE UNIQUE INDEX idx_users_phone_verified ON users (phone)
+WHERE phone_verified = TRUE;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_phone_verified;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Upgrading a password hash to new parameters is not a password change and
+-- must not sign the user out. Rehashes set varaden.password_rehash for their
+-- transaction to keep password_changed_at.
+CREATE OR REPLACE FUNCTION update_users_version_and_timestamp() RETURNS TRIGGER AS $$ BEGIN NEW.updated_at = CURRENT_TIMESTAMP;
+NEW.version = OLD.version + 1;
+IF OLD.password_hash IS DISTINCT
+FROM NEW.password_hash
+    AND COALESCE(current_setting('varaden.password_rehash', TRUE), '') <> 'on' THEN NEW.password_changed_at = CURRENT_TIMESTAMP;
+END IF;
+RETURN NEW;
+END;
+$$ LANGUAGE 'plpgsql';
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_users_version_and_timestamp() RETURNS TRIGGER AS $$ BEGIN NEW.updated_at = CURRENT_TIMESTAMP;
+NEW.version = OLD.version + 1;
+IF OLD.password_hash IS DISTINCT
+FROM NEW.password_hash THEN NEW.password_changed_at = CURRENT_TIMESTAMP;
+END IF;
+RETURN NEW;
+END;
+$$ LANGUAGE 'plpgsql';
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Locks in a row without a successful login; each one doubles the next lock
+ALTER TABLE users
+ADD COLUMN lockout_count INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS lockout_count;
+-- +goose StatementEnd
\ No newline at end of file
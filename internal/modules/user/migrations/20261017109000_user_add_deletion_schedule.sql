@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Accounts deleted by their users are deactivated first and anonymized by the
+-- cron job once the grace period ends
+ALTER TABLE users
+ADD COLUMN deletion_scheduled_at TIMESTAMP,
+    ADD COLUMN anonymized_at TIMESTAMP;
+CREATE INDEX idx_users_deletion_scheduled_at ON users (deletion_scheduled_at)
+WHERE deletion_scheduled_at IS NOT NULL
+    AND anonymized_at IS NULL;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS anonymized_at,
+    DROP COLUMN IF EXISTS deletion_scheduled_at;
+-- +goose StatementEnd
\ No newline at end of file
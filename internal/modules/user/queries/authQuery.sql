@@ -10,13 +10,73 @@ WHERE id = $2;
 UPDATE users
 SET last_login_at = CURRENT_TIMESTAMP,
     failed_login_attempts = 0,
+    lockout_count = 0,
     locked_until = NULL
 WHERE id = $1;
--- name: IncrementFailedLogin :exec
+-- name: IncrementFailedLogin :one
 UPDATE users
-SET failed_login_attempts = failed_login_attempts + 1,
+SET failed_login_attempts = CASE
+        WHEN failed_login_attempts + 1 >= sqlc.arg(threshold)::INT THEN 0
+        ELSE failed_login_attempts + 1
+    END,
+    lockout_count = CASE
+        WHEN failed_login_attempts + 1 >= sqlc.arg(threshold)::INT THEN lockout_count + 1
+        ELSE lockout_count
+    END,
     locked_until = CASE
-        WHEN failed_login_attempts + 1 >= 5 THEN CURRENT_TIMESTAMP + INTERVAL '15 minutes'
+        WHEN failed_login_attempts + 1 >= sqlc.arg(threshold)::INT THEN CURRENT_TIMESTAMP + LEAST(
+            sqlc.arg(lock_seconds)::BIGINT * POWER(2, LEAST(lockout_count, 30)),
+            sqlc.arg(max_lock_seconds)::BIGINT
+        ) * INTERVAL '1 second'
         ELSE locked_until
     END
-WHERE id = $1;
\ No newline at end of file
+WHERE id = sqlc.arg(id)
+RETURNING email,
+    failed_login_attempts = 0 AS locked,
+    locked_until;
+-- name: UnlockUser :exec
+UPDATE users
+SET failed_login_attempts = 0,
+    lockout_count = 0,
+    locked_until = NULL
+WHERE id = $1;
+-- name: CreateOAuthUser :one
+INSERT INTO users (email, password_hash, name, verified_email)
+VALUES ($1, '', $2, $3)
+RETURNING id,
+    email,
+    name,
+    verified_email,
+    is_active;
+-- name: GetUserByPhone :one
+SELECT id,
+    email,
+    name,
+    verified_email,
+    password_hash,
+    is_active,
+    updated_at,
+    last_login_at,
+    locked_until,
+    version
+FROM users
+WHERE phone = $1
+    AND phone_verified = TRUE
+LIMIT 1;
+-- name: GetUserPasswordHashForUpdate :one
+SELECT password_hash
+FROM users
+WHERE id = $1 FOR
+UPDATE;
+-- name: SetVerifiedPhone :exec
+UPDATE users
+SET phone = $1,
+    phone_verified = TRUE
+WHERE id = $2;
+-- name: UpdateEmail :exec
+UPDATE users
+SET email = $1,
+    verified_email = TRUE
+WHERE id = $2;
+-- name: MarkPasswordRehash :exec
+SELECT set_config('varaden.password_rehash', 'on', TRUE);
\ No newline at end of file
@@ -8,7 +8,8 @@ SELECT id,
     updated_at,
     last_login_at,
     locked_until,
-    version
+    version,
+    password_changed_at
 FROM users
 WHERE id = $1
 LIMIT 1;
@@ -38,9 +39,52 @@ RETURNING id,
 -- name: DeleteUser :exec
 UPDATE users
 SET is_active = FALSE,
-    deactivated_at = CURRENT_TIMESTAMP
+    deactivated_at = CURRENT_TIMESTAMP,
+    deletion_scheduled_at = $2
+WHERE id = $1;
+-- name: RestoreUser :execrows
+UPDATE users
+SET is_active = TRUE,
+    deactivated_at = NULL,
+    deletion_scheduled_at = NULL
 WHERE id = $1
-RETURNING id,
+    AND deletion_scheduled_at > CURRENT_TIMESTAMP
+    AND anonymized_at IS NULL;
+-- name: ListUsersDueForAnonymization :many
+SELECT id
+FROM users
+WHERE deletion_scheduled_at <= CURRENT_TIMESTAMP
+    AND anonymized_at IS NULL
+ORDER BY deletion_scheduled_at
+LIMIT $1;
+-- name: AnonymizeUser :exec
+UPDATE users
+SET email = 'deleted-' || id || '@deleted.invalid',
+    name = '',
+    password_hash = '',
+    date_of_birth = NULL,
+    phone = NULL,
+    phone_verified = FALSE,
+    verified_email = FALSE,
+    anonymized_at = CURRENT_TIMESTAMP
+WHERE id = $1
+    AND anonymized_at IS NULL;
+-- name: GetUserExport :one
+SELECT id,
+    email,
+    name,
+    date_of_birth,
+    phone,
+    phone_verified,
+    verified_email,
     is_active,
+    onboarded,
+    created_at,
+    updated_at,
+    last_login_at,
+    password_changed_at,
     deactivated_at,
-    version;
\ No newline at end of file
+    deletion_scheduled_at
+FROM users
+WHERE id = $1
+LIMIT 1;
\ No newline at end of file
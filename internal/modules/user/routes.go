@@ -1,8 +1,18 @@
 package user
 
+import "varaden/server/internal/middlewares"
+
 func (um *UserModule) SetupRoutes() {
-	api := um.route.Group("/users")
+	api := um.route.Group("/users", middlewares.ProtectedWithAPIKey(um.db))
+	// Leaving, taking data out and managing other users are left to the user,
+	// not staff acting as them
+	sensitive := middlewares.DenyImpersonation()
+	// The password confirmation must not be guessable with a stolen token
+	byUser := middlewares.RateLimitByUser(um.limits.AccountLimit, um.limits.AccountWindow)
 
-	api.Get("/", um.getAllUsers)
-	api.Post("/", um.createUser)
+	api.Get("/", sensitive, middlewares.RequirePermission("users:read"), um.getAllUsers)
+	api.Post("/", sensitive, middlewares.RequirePermission("users:write"), um.createUser)
+	api.Get("/me", um.getMe)
+	api.Delete("/me", sensitive, middlewares.RateLimit(um.limiter, "delete-account", byUser), um.deleteAccount)
+	api.Get("/me/export", sensitive, um.exportData)
 }
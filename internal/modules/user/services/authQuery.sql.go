@@ -7,22 +7,169 @@ package userServices
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-const incrementFailedLogin = `-- name: IncrementFailedLogin :exec
+const createOAuthUser = `-- name: CreateOAuthUser :one
+INSERT INTO users (email, password_hash, name, verified_email)
+VALUES ($1, '', $2, $3)
+RETURNING id,
+    email,
+    name,
+    verified_email,
+    is_active
+`
+
+type CreateOAuthUserParams struct {
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	VerifiedEmail bool   `json:"verified_email"`
+}
+
+type CreateOAuthUserRow struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	VerifiedEmail bool      `json:"verified_email"`
+	IsActive      bool      `json:"-"`
+}
+
+func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (CreateOAuthUserRow, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthUser, arg.Email, arg.Name, arg.VerifiedEmail)
+	var i CreateOAuthUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.VerifiedEmail,
+		&i.IsActive,
+	)
+	return i, err
+}
+
+const getUserByPhone = `-- name: GetUserByPhone :one
+SELECT id,
+    email,
+    name,
+    verified_email,
+    password_hash,
+    is_active,
+    updated_at,
+    last_login_at,
+    locked_until,
+    version
+FROM users
+WHERE phone = $1
+    AND phone_verified = TRUE
+LIMIT 1
+`
+
+type GetUserByPhoneRow struct {
+	ID            uuid.UUID    `json:"id"`
+	Email         string       `json:"email"`
+	Name          string       `json:"name"`
+	VerifiedEmail bool         `json:"verified_email"`
+	PasswordHash  string       `json:"-"`
+	IsActive      bool         `json:"-"`
+	UpdatedAt     time.Time    `json:"-"`
+	LastLoginAt   sql.NullTime `json:"-"`
+	LockedUntil   sql.NullTime `json:"-"`
+	Version       int32        `json:"version"`
+}
+
+func (q *Queries) GetUserByPhone(ctx context.Context, phone sql.NullString) (GetUserByPhoneRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserByPhone, phone)
+	var i GetUserByPhoneRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.VerifiedEmail,
+		&i.PasswordHash,
+		&i.IsActive,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.LockedUntil,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getUserPasswordHashForUpdate = `-- name: GetUserPasswordHashForUpdate :one
+SELECT password_hash
+FROM users
+WHERE id = $1 FOR
+UPDATE
+`
+
+func (q *Queries) GetUserPasswordHashForUpdate(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserPasswordHashForUpdate, id)
+	var password_hash string
+	err := row.Scan(&password_hash)
+	return password_hash, err
+}
+
+const incrementFailedLogin = `-- name: IncrementFailedLogin :one
 UPDATE users
-SET failed_login_attempts = failed_login_attempts + 1,
+SET failed_login_attempts = CASE
+        WHEN failed_login_attempts + 1 >= $1::INT THEN 0
+        ELSE failed_login_attempts + 1
+    END,
+    lockout_count = CASE
+        WHEN failed_login_attempts + 1 >= $1::INT THEN lockout_count + 1
+        ELSE lockout_count
+    END,
     locked_until = CASE
-        WHEN failed_login_attempts + 1 >= 5 THEN CURRENT_TIMESTAMP + INTERVAL '15 minutes'
+        WHEN failed_login_attempts + 1 >= $1::INT THEN CURRENT_TIMESTAMP + LEAST(
+            $2::BIGINT * POWER(2, LEAST(lockout_count, 30)),
+            $3::BIGINT
+        ) * INTERVAL '1 second'
         ELSE locked_until
     END
-WHERE id = $1
+WHERE id = $4
+RETURNING email,
+    failed_login_attempts = 0 AS locked,
+    locked_until
+`
+
+type IncrementFailedLoginParams struct {
+	Threshold      int32     `json:"threshold"`
+	LockSeconds    int64     `json:"lock_seconds"`
+	MaxLockSeconds int64     `json:"max_lock_seconds"`
+	ID             uuid.UUID `json:"id"`
+}
+
+type IncrementFailedLoginRow struct {
+	Email       string       `json:"email"`
+	Locked      bool         `json:"locked"`
+	LockedUntil sql.NullTime `json:"-"`
+}
+
+func (q *Queries) IncrementFailedLogin(ctx context.Context, arg IncrementFailedLoginParams) (IncrementFailedLoginRow, error) {
+	row := q.db.QueryRowContext(ctx, incrementFailedLogin,
+		arg.Threshold,
+		arg.LockSeconds,
+		arg.MaxLockSeconds,
+		arg.ID,
+	)
+	var i IncrementFailedLoginRow
+	err := row.Scan(
+		&i.Email,
+		&i.Locked,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const markPasswordRehash = `-- name: MarkPasswordRehash :exec
+SELECT set_config('varaden.password_rehash', 'on', TRUE)
 `
 
-func (q *Queries) IncrementFailedLogin(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, incrementFailedLogin, id)
+func (q *Queries) MarkPasswordRehash(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, markPasswordRehash)
 	return err
 }
 
@@ -30,6 +177,7 @@ const resetFailedLogin = `-- name: ResetFailedLogin :exec
 UPDATE users
 SET last_login_at = CURRENT_TIMESTAMP,
     failed_login_attempts = 0,
+    lockout_count = 0,
     locked_until = NULL
 WHERE id = $1
 `
@@ -39,6 +187,53 @@ func (q *Queries) ResetFailedLogin(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const setVerifiedPhone = `-- name: SetVerifiedPhone :exec
+UPDATE users
+SET phone = $1,
+    phone_verified = TRUE
+WHERE id = $2
+`
+
+type SetVerifiedPhoneParams struct {
+	Phone sql.NullString `json:"phone"`
+	ID    uuid.UUID      `json:"id"`
+}
+
+func (q *Queries) SetVerifiedPhone(ctx context.Context, arg SetVerifiedPhoneParams) error {
+	_, err := q.db.ExecContext(ctx, setVerifiedPhone, arg.Phone, arg.ID)
+	return err
+}
+
+const unlockUser = `-- name: UnlockUser :exec
+UPDATE users
+SET failed_login_attempts = 0,
+    lockout_count = 0,
+    locked_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unlockUser, id)
+	return err
+}
+
+const updateEmail = `-- name: UpdateEmail :exec
+UPDATE users
+SET email = $1,
+    verified_email = TRUE
+WHERE id = $2
+`
+
+type UpdateEmailParams struct {
+	Email string    `json:"email"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateEmail(ctx context.Context, arg UpdateEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateEmail, arg.Email, arg.ID)
+	return err
+}
+
 const updatePassword = `-- name: UpdatePassword :exec
 UPDATE users
 SET password_hash = $1
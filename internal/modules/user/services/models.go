@@ -30,4 +30,8 @@ type User struct {
 	LastLoginAt         sql.NullTime   `json:"-"`
 	FailedLoginAttempts int32          `json:"failed_login_attempts"`
 	LockedUntil         sql.NullTime   `json:"-"`
+	PhoneVerified       bool           `json:"phone_verified"`
+	LockoutCount        int32          `json:"-"`
+	DeletionScheduledAt sql.NullTime   `json:"deletion_scheduled_at"`
+	AnonymizedAt        sql.NullTime   `json:"anonymized_at"`
 }
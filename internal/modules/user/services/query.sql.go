@@ -13,6 +13,25 @@ import (
 	"github.com/google/uuid"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users
+SET email = 'deleted-' || id || '@deleted.invalid',
+    name = '',
+    password_hash = '',
+    date_of_birth = NULL,
+    phone = NULL,
+    phone_verified = FALSE,
+    verified_email = FALSE,
+    anonymized_at = CURRENT_TIMESTAMP
+WHERE id = $1
+    AND anonymized_at IS NULL
+`
+
+func (q *Queries) AnonymizeUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUser, id)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, password_hash)
 VALUES ($1, $2)
@@ -40,21 +59,23 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 const deleteUser = `-- name: DeleteUser :exec
 UPDATE users
 SET is_active = FALSE,
-    deactivated_at = CURRENT_TIMESTAMP
+    deactivated_at = CURRENT_TIMESTAMP,
+    deletion_scheduled_at = $2
 WHERE id = $1
-RETURNING id,
-    is_active,
-    deactivated_at,
-    version
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
+type DeleteUserParams struct {
+	ID                  uuid.UUID    `json:"id"`
+	DeletionScheduledAt sql.NullTime `json:"deletion_scheduled_at"`
+}
+
+func (q *Queries) DeleteUser(ctx context.Context, arg DeleteUserParams) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, arg.ID, arg.DeletionScheduledAt)
 	return err
 }
 
 const getAllUsers = `-- name: GetAllUsers :many
-SELECT id, email, email_normalized, password_hash, password_changed_at, name, date_of_birth, phone, verified_email, is_active, onboarded, deactivated_at, created_at, updated_at, version, last_login_at, failed_login_attempts, locked_until
+SELECT id, email, email_normalized, password_hash, password_changed_at, name, date_of_birth, phone, verified_email, is_active, onboarded, deactivated_at, created_at, updated_at, version, last_login_at, failed_login_attempts, locked_until, phone_verified, lockout_count, deletion_scheduled_at, anonymized_at
 FROM users
 ORDER BY name
 `
@@ -87,6 +108,10 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 			&i.LastLoginAt,
 			&i.FailedLoginAttempts,
 			&i.LockedUntil,
+			&i.PhoneVerified,
+			&i.LockoutCount,
+			&i.DeletionScheduledAt,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -123,9 +148,9 @@ type GetUserByEmailRow struct {
 	Name          string       `json:"name"`
 	VerifiedEmail bool         `json:"verified_email"`
 	PasswordHash  string       `json:"-"`
-	IsActive      bool         `json:"-"`
-	UpdatedAt     time.Time    `json:"-"`
-	LastLoginAt   sql.NullTime `json:"-"`
+	IsActive      bool         `json:"is_active"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	LastLoginAt   sql.NullTime `json:"last_login_at"`
 	LockedUntil   sql.NullTime `json:"-"`
 	Version       int32        `json:"version"`
 }
@@ -158,23 +183,25 @@ SELECT id,
     updated_at,
     last_login_at,
     locked_until,
-    version
+    version,
+    password_changed_at
 FROM users
 WHERE id = $1
 LIMIT 1
 `
 
 type GetUserByIdRow struct {
-	ID            uuid.UUID    `json:"id"`
-	Email         string       `json:"email"`
-	Name          string       `json:"name"`
-	VerifiedEmail bool         `json:"verified_email"`
-	IsActive      bool         `json:"-"`
-	PasswordHash  string       `json:"-"`
-	UpdatedAt     time.Time    `json:"-"`
-	LastLoginAt   sql.NullTime `json:"-"`
-	LockedUntil   sql.NullTime `json:"-"`
-	Version       int32        `json:"version"`
+	ID                uuid.UUID    `json:"id"`
+	Email             string       `json:"email"`
+	Name              string       `json:"name"`
+	VerifiedEmail     bool         `json:"verified_email"`
+	IsActive          bool         `json:"is_active"`
+	PasswordHash      string       `json:"-"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	LastLoginAt       sql.NullTime `json:"last_login_at"`
+	LockedUntil       sql.NullTime `json:"-"`
+	Version           int32        `json:"version"`
+	PasswordChangedAt time.Time    `json:"password_changed_at"`
 }
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow, error) {
@@ -191,6 +218,119 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow
 		&i.LastLoginAt,
 		&i.LockedUntil,
 		&i.Version,
+		&i.PasswordChangedAt,
+	)
+	return i, err
+}
+
+const getUserExport = `-- name: GetUserExport :one
+SELECT id,
+    email,
+    name,
+    date_of_birth,
+    phone,
+    phone_verified,
+    verified_email,
+    is_active,
+    onboarded,
+    created_at,
+    updated_at,
+    last_login_at,
+    password_changed_at,
+    deactivated_at,
+    deletion_scheduled_at
+FROM users
+WHERE id = $1
+LIMIT 1
+`
+
+type GetUserExportRow struct {
+	ID                  uuid.UUID      `json:"id"`
+	Email               string         `json:"email"`
+	Name                string         `json:"name"`
+	DateOfBirth         sql.NullTime   `json:"date_of_birth"`
+	Phone               sql.NullString `json:"phone"`
+	PhoneVerified       bool           `json:"phone_verified"`
+	VerifiedEmail       bool           `json:"verified_email"`
+	IsActive            bool           `json:"-"`
+	Onboarded           bool           `json:"onboarded"`
+	CreatedAt           time.Time      `json:"-"`
+	UpdatedAt           time.Time      `json:"-"`
+	LastLoginAt         sql.NullTime   `json:"-"`
+	PasswordChangedAt   time.Time      `json:"password_changed_at"`
+	DeactivatedAt       sql.NullTime   `json:"deactivated_at"`
+	DeletionScheduledAt sql.NullTime   `json:"deletion_scheduled_at"`
+}
+
+func (q *Queries) GetUserExport(ctx context.Context, id uuid.UUID) (GetUserExportRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserExport, id)
+	var i GetUserExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.DateOfBirth,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.VerifiedEmail,
+		&i.IsActive,
+		&i.Onboarded,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.PasswordChangedAt,
+		&i.DeactivatedAt,
+		&i.DeletionScheduledAt,
 	)
 	return i, err
 }
+
+const listUsersDueForAnonymization = `-- name: ListUsersDueForAnonymization :many
+SELECT id
+FROM users
+WHERE deletion_scheduled_at <= CURRENT_TIMESTAMP
+    AND anonymized_at IS NULL
+ORDER BY deletion_scheduled_at
+LIMIT $1
+`
+
+func (q *Queries) ListUsersDueForAnonymization(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersDueForAnonymization, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreUser = `-- name: RestoreUser :execrows
+UPDATE users
+SET is_active = TRUE,
+    deactivated_at = NULL,
+    deletion_scheduled_at = NULL
+WHERE id = $1
+    AND deletion_scheduled_at > CURRENT_TIMESTAMP
+    AND anonymized_at IS NULL
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
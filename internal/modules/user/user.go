@@ -2,6 +2,11 @@ package user
 
 import (
 	"database/sql"
+	"varaden/server/config"
+	authServices "varaden/server/internal/modules/auth/services"
+	rbacServices "varaden/server/internal/modules/rbac/services"
+	userServices "varaden/server/internal/modules/user/services"
+	"varaden/server/internal/services"
 	"varaden/server/internal/utils"
 
 	"github.com/go-playground/validator/v10"
@@ -12,12 +17,28 @@ type UserModule struct {
 	db       *sql.DB
 	route    fiber.Router
 	validate *validator.Validate
+	email    services.EmailService
+	limiter  services.RateLimitStore
+	limits   config.RateLimitConfig
+	account  config.AccountConfig
+	user     *userServices.Queries
+	auth     *authServices.Queries
+	rbac     *rbacServices.Queries
+	jwt      *utils.JWTConfig
 }
 
-func RegisterUserModule(route fiber.Router, db *sql.DB) *UserModule {
+func RegisterUserModule(route fiber.Router, db *sql.DB, emailService services.EmailService, rateLimitStore services.RateLimitStore, rateLimitConfig config.RateLimitConfig, accountConfig config.AccountConfig) *UserModule {
 	return &UserModule{
 		db:       db,
 		route:    route,
 		validate: utils.Validator(),
+		email:    emailService,
+		limiter:  rateLimitStore,
+		limits:   rateLimitConfig,
+		account:  accountConfig,
+		user:     userServices.New(db),
+		auth:     authServices.New(db),
+		rbac:     rbacServices.New(db),
+		jwt:      config.JWTConfig,
 	}
 }
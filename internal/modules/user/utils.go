@@ -0,0 +1,88 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"varaden/server/config"
+	"varaden/server/internal/middlewares"
+	authServices "varaden/server/internal/modules/auth/services"
+	"varaden/server/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+)
+
+// Events recorded in the auth_events audit log.
+const (
+	eventAccountDelete = "account_delete"
+	eventDataExport    = "data_export"
+)
+
+func (um *UserModule) SendAccountDeletedEmail(to string, deletionAt time.Time, restoreToken string) error {
+	subject := "Your account was deleted"
+
+	body := fmt.Sprintf(`
+Dear user,
+
+Your account was deleted and you were signed out of every device. Your personal data will be erased on %s.
+`, deletionAt.Format("2 January 2006"))
+	// Without a grace period there is nothing left to restore
+	if restoreToken != "" {
+		restoreURL := fmt.Sprintf("%s/restore-account?token=%s", config.FrontEndURL, restoreToken)
+		body += fmt.Sprintf(`
+If you change your mind before then, click on this link to restore your account: %s
+`, restoreURL)
+	}
+	return um.email.SendEmail(to, subject, body)
+}
+
+// logEvent records that event succeeded for the signed-in user. A failed
+// write is logged rather than failing the request.
+func (um *UserModule) logEvent(ctx context.Context, c *fiber.Ctx, event string, principal *middlewares.Principal) {
+	if err := um.auth.CreateAuthEvent(ctx, authServices.CreateAuthEventParams{
+		Event:     event,
+		Outcome:   "success",
+		UserID:    uuid.NullUUID{UUID: principal.ID, Valid: true},
+		ActorID:   uuid.NullUUID{UUID: principal.ID, Valid: true},
+		IpAddress: c.IP(),
+		UserAgent: utils.Truncate(c.Get(fiber.HeaderUserAgent), 512),
+	}); err != nil {
+		log.Errorf("failed to record %s event for user %s: %v", event, principal.ID, err)
+	}
+}
+
+// nullTime turns a nullable timestamp into one that encodes as null in JSON.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullString turns a nullable string into one that encodes as null in JSON.
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// nonNil makes an empty list encode as [] rather than null in JSON.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// exportList describes each item with describe, leaving out its secrets.
+func exportList[T any](items []T, describe func(T) fiber.Map) []fiber.Map {
+	data := make([]fiber.Map, 0, len(items))
+	for _, item := range items {
+		data = append(data, describe(item))
+	}
+	return data
+}
@@ -1 +1,5 @@
 package user
+
+type deleteAccountData struct {
+	Password string `json:"password" validate:"required,max=100" example:"password1"`
+}
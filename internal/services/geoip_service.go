@@ -0,0 +1,72 @@
+package services
+
+import (
+	"net"
+	"varaden/server/config"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoLocation is the approximate location of an IP address.
+type GeoLocation struct {
+	City        string
+	Country     string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
+	// AccuracyKM is the radius around the coordinates that the address is
+	// likely to be in.
+	AccuracyKM int
+}
+
+type GeoIPService interface {
+	// Lookup returns the location of ip, or false when it is unknown.
+	Lookup(ip string) (GeoLocation, bool)
+}
+
+// NewGeoIPService loads the GeoIP database file from the config. Without one,
+// every address has an unknown location.
+func NewGeoIPService(config *config.LoginRiskConfig) (GeoIPService, error) {
+	if config.GeoIPDB == "" {
+		return noGeoIPService{}, nil
+	}
+
+	reader, err := geoip2.Open(config.GeoIPDB)
+	if err != nil {
+		return nil, err
+	}
+	return &maxMindGeoIPService{reader: reader}, nil
+}
+
+// maxMindGeoIPService looks addresses up in a local MaxMind City database.
+type maxMindGeoIPService struct {
+	reader *geoip2.Reader
+}
+
+func (gs *maxMindGeoIPService) Lookup(ip string) (GeoLocation, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return GeoLocation{}, false
+	}
+
+	city, err := gs.reader.City(addr)
+	// Private and reserved ranges are not in the database
+	if err != nil || city.Country.IsoCode == "" {
+		return GeoLocation{}, false
+	}
+
+	return GeoLocation{
+		City:        city.City.Names["en"],
+		Country:     city.Country.Names["en"],
+		CountryCode: city.Country.IsoCode,
+		Latitude:    city.Location.Latitude,
+		Longitude:   city.Location.Longitude,
+		AccuracyKM:  int(city.Location.AccuracyRadius),
+	}, true
+}
+
+type noGeoIPService struct{}
+
+func (noGeoIPService) Lookup(string) (GeoLocation, bool) {
+	return GeoLocation{}, false
+}
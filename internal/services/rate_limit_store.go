@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+	"varaden/server/config"
+)
+
+// RateLimitStore counts requests in fixed windows. Rate limiters weigh the
+// previous window's count by how much of it still overlaps the sliding window.
+type RateLimitStore interface {
+	// Hit counts a request for key in the window of the given length that
+	// starts at window, and returns the hits counted in it so far, including
+	// this one, and in the window before it.
+	Hit(ctx context.Context, key string, window time.Time, length time.Duration) (current, previous int64, err error)
+}
+
+// RateLimitCounters is the table the postgres store keeps its counters in. The
+// auth module, which owns the table, provides it.
+type RateLimitCounters interface {
+	// Hit counts a request for key in the window starting at windowStart, in
+	// Unix seconds, and returns the hits counted in it so far.
+	Hit(ctx context.Context, key string, windowStart int64, expiresAt time.Time) (int64, error)
+	// Hits returns the hits counted for key in the window starting at
+	// windowStart, or 0 when there were none.
+	Hits(ctx context.Context, key string, windowStart int64) (int64, error)
+	// DeleteExpired drops counters of windows that no longer affect any limit.
+	DeleteExpired(ctx context.Context) error
+}
+
+// rateLimitSweepInterval is how often stores drop counters of past windows.
+const rateLimitSweepInterval = time.Minute
+
+func NewRateLimitStore(config *config.RateLimitConfig, counters RateLimitCounters) RateLimitStore {
+	if config.Store == "postgres" {
+		return &postgresRateLimitStore{counters: counters}
+	}
+	return &memoryRateLimitStore{counters: make(map[string]*rateLimitCounter)}
+}
+
+type rateLimitCounter struct {
+	window   time.Time
+	length   time.Duration
+	current  int64
+	previous int64
+}
+
+// memoryRateLimitStore keeps counters in process. Limits are per instance and
+// reset when it restarts.
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	counters  map[string]*rateLimitCounter
+	lastSweep time.Time
+}
+
+func (s *memoryRateLimitStore) Hit(ctx context.Context, key string, window time.Time, length time.Duration) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+
+	counter, ok := s.counters[key]
+	switch {
+	case !ok:
+		counter = &rateLimitCounter{window: window, length: length}
+		s.counters[key] = counter
+	case counter.window.Equal(window):
+	case counter.window.Add(length).Equal(window):
+		counter.window, counter.previous, counter.current = window, counter.current, 0
+	default:
+		counter.window, counter.previous, counter.current = window, 0, 0
+	}
+	counter.current++
+
+	return counter.current, counter.previous, nil
+}
+
+// sweep drops counters that no longer affect any limit. The caller holds mu.
+func (s *memoryRateLimitStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, counter := range s.counters {
+		if now.After(counter.window.Add(2 * counter.length)) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// postgresRateLimitStore keeps counters in the rate_limit_hits table, so every
+// instance shares them and they survive restarts.
+type postgresRateLimitStore struct {
+	counters RateLimitCounters
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func (s *postgresRateLimitStore) Hit(ctx context.Context, key string, window time.Time, length time.Duration) (int64, int64, error) {
+	if err := s.sweep(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	current, err := s.counters.Hit(ctx, key, window.Unix(), window.Add(2*length))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	previous, err := s.counters.Hits(ctx, key, window.Add(-length).Unix())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return current, previous, nil
+}
+
+func (s *postgresRateLimitStore) sweep(ctx context.Context) error {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < rateLimitSweepInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	return s.counters.DeleteExpired(ctx)
+}
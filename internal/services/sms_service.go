@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	"varaden/server/config"
+)
+
+type SMSService interface {
+	SendSMS(to, body string) error
+}
+
+// logSMSService stands in for an SMS gateway during development. It logs who
+// each text message went to and writes the message itself, one-time codes
+// included, only to the file when one is configured.
+type logSMSService struct {
+	mu       sync.Mutex
+	filePath string
+}
+
+// NewSMSService returns the development sender. No SMS gateway is wired in
+// yet, so outside development it fails rather than drop messages or let
+// one-time codes reach the logs.
+func NewSMSService(smsConfig *config.SMSConfig) (SMSService, error) {
+	if !config.IsDevelopment {
+		return nil, errors.New("no SMS gateway is configured; text messages can only be logged in development")
+	}
+
+	return &logSMSService{
+		filePath: smsConfig.LogFile,
+	}, nil
+}
+
+func (ss *logSMSService) SendSMS(to, body string) error {
+	slog.Info("SMS sent", "to", to)
+
+	if ss.filePath == "" {
+		return nil
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	file, err := os.OpenFile(ss.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), to, body)
+	return err
+}
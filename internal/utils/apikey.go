@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key so leaked keys are easy to recognise, e.g.
+// by secret scanners.
+const APIKeyPrefix = "vrd_"
+
+// apiKeyIDLen is the length of the random ID after APIKeyPrefix. Together they
+// form the public part of a key that is stored in clear and shown in lists.
+const apiKeyIDLen = 8
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateAPIKey returns a new API key of the form vrd_<id>_<secret> and its
+// public prefix vrd_<id>. Only the prefix and HashAPIKey(key) should be stored.
+func GenerateAPIKey() (key, prefix string, err error) {
+	id := make([]byte, apiKeyIDLen/2)
+	secret := make([]byte, 20)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + strings.ToLower(apiKeyEncoding.EncodeToString(secret))
+	return key, prefix, nil
+}
+
+// ParseAPIKey returns the public prefix of an API key, or false when the key
+// is not in the format produced by GenerateAPIKey.
+func ParseAPIKey(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok || len(rest) <= apiKeyIDLen+1 || rest[apiKeyIDLen] != '_' {
+		return "", false
+	}
+	return key[:len(APIKeyPrefix)+apiKeyIDLen], true
+}
+
+// HashAPIKey returns the hex SHA-256 of an API key. Keys carry 160 random
+// bits, so a fast hash is enough to make a leaked table useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "crypto/hmac"
+
+// CSRFHeaderName is the header that requests authenticated by the refresh
+// token cookie must echo the CSRF token in.
+const CSRFHeaderName = "X-CSRF-Token"
+
+// CSRFToken derives the CSRF token bound to a refresh token. It changes
+// whenever the refresh token is rotated, and only the server can make it, so
+// a page on another site can neither read nor forge it.
+func (j *JWTConfig) CSRFToken(refreshToken string) string {
+	return HashToken(j.CSRFSecret, "csrf:"+refreshToken)
+}
+
+// CheckCSRFToken reports in constant time whether csrfToken is the one bound
+// to refreshToken.
+func (j *JWTConfig) CheckCSRFToken(refreshToken, csrfToken string) bool {
+	return hmac.Equal([]byte(j.CSRFToken(refreshToken)), []byte(csrfToken))
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -34,7 +35,7 @@ var customMessages = map[string]string{
 	"positive": "Field %s must be a positive number",
 	"alphanum": "Field %s must contain only alphanumeric characters",
 	"oneof":    "Invalid value for field %s",
-	"password": "Field %s must contain at least 1 letter and 1 number",
+	"password": "Field %s does not meet the password policy",
 }
 
 func ErrorHandler(c *fiber.Ctx, err error) error {
@@ -91,6 +92,10 @@ func CustomErrorMessages(err error) map[string]string {
 	if errors.As(err, &validationErrors) {
 		return generateErrorMessages(validationErrors)
 	}
+	var policyError *PasswordPolicyError
+	if errors.As(err, &policyError) {
+		return map[string]string{policyError.Field: strings.Join(policyError.Violations, " ")}
+	}
 	return nil
 }
 
@@ -9,7 +9,10 @@ import (
 	"github.com/google/uuid"
 )
 
-var tokenType = "access"
+var (
+	tokenType        = "access"
+	refreshTokenType = "refresh"
+)
 
 type JWTConfig struct {
 	Issuer              string
@@ -20,6 +23,16 @@ type JWTConfig struct {
 	RefreshCookieDomain string
 	RefreshCookiePath   string
 	RefreshCookieName   string
+	// CSRFCookieName carries the CSRF token bound to the refresh token, keyed
+	// with CSRFSecret.
+	CSRFCookieName string
+	CSRFSecret     string
+	// ImpersonationExpiry is how long, in minutes, an access token issued to
+	// support staff acting as another user lasts.
+	ImpersonationExpiry int
+	// Keys signs and verifies tokens with RS256/EdDSA when set; otherwise
+	// tokens are signed with HS256 using Secret.
+	Keys *JWTKeySet
 }
 
 type TokenPair struct {
@@ -27,30 +40,61 @@ type TokenPair struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-func (j *JWTConfig) GenerateToken(id uuid.UUID) (TokenPair, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+// AccessClaims identifies the user and the session an access token was issued for.
+type AccessClaims struct {
+	Subject   string    // user ID (sub)
+	SessionID string    // token family ID (sid)
+	IssuedAt  time.Time // iat
+	// ActorID is the staff member acting as the user (act.sub) in an
+	// impersonation token, and empty otherwise.
+	ActorID string
+}
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["sub"] = fmt.Sprint(id)
-	claims["aud"] = j.Audience
-	claims["iss"] = j.Issuer
-	claims["iat"] = time.Now().UTC().Unix()
-	claims["typ"] = tokenType
-	claims["exp"] = time.Now().UTC().Add(time.Duration(j.TokenExpiry) * time.Hour).Unix()
+// RefreshClaims identifies the user and the server-side session a refresh token belongs to.
+type RefreshClaims struct {
+	Subject   string    // user ID (sub)
+	TokenID   string    // session row ID (jti)
+	SessionID string    // token family ID (sid)
+	IssuedAt  time.Time // iat
+}
 
-	signedAccessToken, err := token.SignedString([]byte(j.Secret))
+// IssuedBefore reports whether the access token was issued before t, e.g.
+// before the user's password last changed.
+func (c AccessClaims) IssuedBefore(t time.Time) bool {
+	return issuedBefore(c.IssuedAt, t)
+}
 
+// IssuedBefore reports whether the refresh token was issued before t.
+func (c RefreshClaims) IssuedBefore(t time.Time) bool {
+	return issuedBefore(c.IssuedAt, t)
+}
+
+// issuedBefore compares at the one-second resolution of iat, so tokens issued
+// in the same second as t, such as the ones handed out by the request that
+// changed the password, are not considered older.
+func issuedBefore(iat, t time.Time) bool {
+	return iat.Before(t.Truncate(time.Second))
+}
+
+// GenerateToken signs an access token and a refresh token for the user. jti is the
+// ID of the session row backing the refresh token and sid the token family it
+// belongs to; both are embedded so the session can be rotated or revoked.
+func (j *JWTConfig) GenerateToken(id, jti, sid uuid.UUID) (TokenPair, error) {
+	signedAccessToken, err := j.signAccessToken(id, sid, time.Duration(j.TokenExpiry)*time.Hour, nil)
 	if err != nil {
-		return TokenPair{}, fmt.Errorf("failed to sign access token: %w", err)
+		return TokenPair{}, err
 	}
 
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
+	refreshToken := j.newToken()
 	refreshClaims := refreshToken.Claims.(jwt.MapClaims)
 	refreshClaims["sub"] = fmt.Sprint(id)
+	refreshClaims["jti"] = fmt.Sprint(jti)
+	refreshClaims["sid"] = fmt.Sprint(sid)
+	refreshClaims["typ"] = refreshTokenType
 	refreshClaims["iat"] = time.Now().UTC().Unix()
 	refreshClaims["exp"] = time.Now().UTC().Add(time.Duration(j.RefreshExpiry) * 24 * time.Hour).Unix()
 
-	signedRefreshToken, err := refreshToken.SignedString([]byte(j.Secret))
+	signedRefreshToken, err := j.signedString(refreshToken)
 	if err != nil {
 		return TokenPair{}, fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -63,6 +107,75 @@ func (j *JWTConfig) GenerateToken(id uuid.UUID) (TokenPair, error) {
 	return TokenPair, nil
 }
 
+// GenerateImpersonationToken signs an access token for the user that names
+// actorID, the staff member acting as them, in an RFC 8693 act claim. It
+// lasts ImpersonationExpiry minutes and comes without a refresh token, so an
+// impersonation cannot be extended.
+func (j *JWTConfig) GenerateImpersonationToken(id, sid, actorID uuid.UUID) (string, error) {
+	return j.signAccessToken(id, sid, time.Duration(j.ImpersonationExpiry)*time.Minute, jwt.MapClaims{
+		"act": map[string]any{"sub": fmt.Sprint(actorID)},
+	})
+}
+
+// signAccessToken signs an access token for the user in token family sid
+// that expires after ttl, with any extra claims.
+func (j *JWTConfig) signAccessToken(id, sid uuid.UUID, ttl time.Duration, extra jwt.MapClaims) (string, error) {
+	token := j.newToken()
+
+	claims := token.Claims.(jwt.MapClaims)
+	for key, value := range extra {
+		claims[key] = value
+	}
+	claims["sub"] = fmt.Sprint(id)
+	claims["aud"] = j.Audience
+	claims["iss"] = j.Issuer
+	claims["iat"] = time.Now().UTC().Unix()
+	claims["typ"] = tokenType
+	claims["sid"] = fmt.Sprint(sid)
+	claims["exp"] = time.Now().UTC().Add(ttl).Unix()
+
+	signedToken, err := j.signedString(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return signedToken, nil
+}
+
+// newToken starts a token for the active signing key, with its ID in the kid
+// header.
+func (j *JWTConfig) newToken() *jwt.Token {
+	if j.Keys == nil {
+		return jwt.New(jwt.SigningMethodHS256)
+	}
+
+	token := jwt.New(j.Keys.signing.method)
+	token.Header["kid"] = j.Keys.signing.ID
+	return token
+}
+
+func (j *JWTConfig) signedString(token *jwt.Token) (string, error) {
+	if j.Keys == nil {
+		return token.SignedString([]byte(j.Secret))
+	}
+	return token.SignedString(j.Keys.signing.private)
+}
+
+// keyFunc returns the key to verify a token with: the key named by its kid
+// header, or the shared secret when no keys are configured.
+func (j *JWTConfig) keyFunc(token *jwt.Token) (interface{}, error) {
+	if j.Keys != nil {
+		return j.Keys.verificationKey(token)
+	}
+
+	// Ensure the signing method is what we expect (e.g., HMAC with SHA256)
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return []byte(j.Secret), nil
+}
+
+// SetRefreshCookie sets the HTTP-only refresh token cookie, along with a CSRF
+// token bound to it in a cookie scripts can read and the X-CSRF-Token header.
 func (j *JWTConfig) SetRefreshCookie(c *fiber.Ctx, token string) {
 	cookie := new(fiber.Cookie)
 	cookie.Name = j.RefreshCookieName
@@ -77,6 +190,14 @@ func (j *JWTConfig) SetRefreshCookie(c *fiber.Ctx, token string) {
 
 	// Set the cookie in the response
 	c.Cookie(cookie)
+
+	csrfToken := j.CSRFToken(token)
+	csrfCookie := *cookie
+	csrfCookie.Name = j.CSRFCookieName
+	csrfCookie.Value = csrfToken
+	csrfCookie.HTTPOnly = false
+	c.Cookie(&csrfCookie)
+	c.Set(CSRFHeaderName, csrfToken)
 }
 
 func (j *JWTConfig) GetExpiredRefreshCookie(c *fiber.Ctx) {
@@ -94,29 +215,28 @@ func (j *JWTConfig) GetExpiredRefreshCookie(c *fiber.Ctx) {
 
 	// Set the cookie in the response
 	c.Cookie(cookie)
+
+	csrfCookie := *cookie
+	csrfCookie.Name = j.CSRFCookieName
+	csrfCookie.HTTPOnly = false
+	c.Cookie(&csrfCookie)
 }
 
-func (j *JWTConfig) AccessTokenValidate(tokenStr string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is what we expect (e.g., HMAC with SHA256)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(j.Secret), nil
-	})
+func (j *JWTConfig) AccessTokenValidate(tokenStr string) (AccessClaims, error) {
+	token, err := jwt.Parse(tokenStr, j.keyFunc)
 
 	if err != nil {
 		// jwt.Parse returns errors for invalid signatures, malformed tokens, etc.
-		return "", fmt.Errorf("failed to parse token: %w", err)
+		return AccessClaims{}, fmt.Errorf("failed to parse token: %w", err)
 	}
 
 	if !token.Valid {
-		return "", fmt.Errorf("token is not valid")
+		return AccessClaims{}, fmt.Errorf("token is not valid")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", fmt.Errorf("invalid token claims format")
+		return AccessClaims{}, fmt.Errorf("invalid token claims format")
 	}
 
 	// --- Manual validation of standard claims (in case jwt lib skips some) ---
@@ -125,64 +245,79 @@ func (j *JWTConfig) AccessTokenValidate(tokenStr string) (string, error) {
 
 	// 1. Validate "exp" (expiration time)
 	if exp, ok := claims["exp"].(float64); !ok || now >= int64(exp) {
-		return "", fmt.Errorf("token is expired")
+		return AccessClaims{}, fmt.Errorf("token is expired")
 	}
 
 	// 2. Validate "nbf" (not before) – optional but good practice
 	if nbf, ok := claims["nbf"].(float64); ok && now < int64(nbf) {
-		return "", fmt.Errorf("token not yet valid")
+		return AccessClaims{}, fmt.Errorf("token not yet valid")
 	}
 
 	// 3. Validate "iat" (issued at) – optional sanity check
 	if iat, ok := claims["iat"].(float64); ok && now < int64(iat) {
-		return "", fmt.Errorf("token issued in the future")
+		return AccessClaims{}, fmt.Errorf("token issued in the future")
 	}
 
 	// 4. Validate "iss" (issuer)
 	if iss, ok := claims["iss"].(string); !ok || iss != j.Issuer {
-		return "", fmt.Errorf("invalid token issuer")
+		return AccessClaims{}, fmt.Errorf("invalid token issuer")
 	}
 
 	// 5. Validate "aud" (audience)
 	if aud, ok := claims["aud"].(string); !ok || aud != j.Audience {
-		return "", fmt.Errorf("invalid token audience")
+		return AccessClaims{}, fmt.Errorf("invalid token audience")
 	}
 
 	// 6. Validate custom "typ" claim (your token type, e.g., "access")
 	if typ, ok := claims["typ"].(string); !ok || typ != tokenType {
-		return "", fmt.Errorf("invalid token type")
+		return AccessClaims{}, fmt.Errorf("invalid token type")
 	}
 
 	// 7. Validate "sub" (subject/user ID)
 	sub, ok := claims["sub"].(string)
 	if !ok || sub == "" {
-		return "", fmt.Errorf("invalid or missing subject (sub) claim")
+		return AccessClaims{}, fmt.Errorf("invalid or missing subject (sub) claim")
 	}
 
-	return sub, nil
-}
+	// 8. Validate "sid" (session/token family ID)
+	sid, ok := claims["sid"].(string)
+	if !ok || sid == "" {
+		return AccessClaims{}, fmt.Errorf("invalid or missing session ID (sid) claim")
+	}
 
-func (j *JWTConfig) RefreshTokenValidate(tokenStr string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is what we expect (e.g., HMAC with SHA256)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	// 9. Validate "act" (actor), present on impersonation tokens only
+	var actorID string
+	if act, ok := claims["act"]; ok {
+		actor, _ := act.(map[string]any)
+		actorID, _ = actor["sub"].(string)
+		if actorID == "" {
+			return AccessClaims{}, fmt.Errorf("invalid actor (act) claim")
 		}
-		return []byte(j.Secret), nil
-	})
+	}
+
+	return AccessClaims{
+		Subject:   sub,
+		SessionID: sid,
+		IssuedAt:  issuedAt(claims),
+		ActorID:   actorID,
+	}, nil
+}
+
+func (j *JWTConfig) RefreshTokenValidate(tokenStr string) (RefreshClaims, error) {
+	token, err := jwt.Parse(tokenStr, j.keyFunc)
 
 	if err != nil {
 		// jwt.Parse returns errors for invalid signatures, malformed tokens, etc.
-		return "", fmt.Errorf("failed to parse token: %w", err)
+		return RefreshClaims{}, fmt.Errorf("failed to parse token: %w", err)
 	}
 
 	if !token.Valid {
-		return "", fmt.Errorf("token is not valid")
+		return RefreshClaims{}, fmt.Errorf("token is not valid")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", fmt.Errorf("invalid token claims format")
+		return RefreshClaims{}, fmt.Errorf("invalid token claims format")
 	}
 
 	// --- Manual validation of standard claims (in case jwt lib skips some) ---
@@ -191,24 +326,102 @@ func (j *JWTConfig) RefreshTokenValidate(tokenStr string) (string, error) {
 
 	// 1. Validate "exp" (expiration time)
 	if exp, ok := claims["exp"].(float64); !ok || now >= int64(exp) {
-		return "", fmt.Errorf("token is expired")
+		return RefreshClaims{}, fmt.Errorf("token is expired")
 	}
 
 	// 2. Validate "nbf" (not before) – optional but good practice
 	if nbf, ok := claims["nbf"].(float64); ok && now < int64(nbf) {
-		return "", fmt.Errorf("token not yet valid")
+		return RefreshClaims{}, fmt.Errorf("token not yet valid")
 	}
 
 	// 3. Validate "iat" (issued at) – optional sanity check
 	if iat, ok := claims["iat"].(float64); ok && now < int64(iat) {
-		return "", fmt.Errorf("token issued in the future")
+		return RefreshClaims{}, fmt.Errorf("token issued in the future")
 	}
 
-	// 4. Validate "sub" (subject/user ID)
+	// 4. Validate custom "typ" claim
+	if typ, ok := claims["typ"].(string); !ok || typ != refreshTokenType {
+		return RefreshClaims{}, fmt.Errorf("invalid token type")
+	}
+
+	// 5. Validate "sub" (subject/user ID)
 	sub, ok := claims["sub"].(string)
 	if !ok || sub == "" {
-		return "", fmt.Errorf("invalid or missing subject (sub) claim")
+		return RefreshClaims{}, fmt.Errorf("invalid or missing subject (sub) claim")
+	}
+
+	// 6. Validate "jti" and "sid" (session row and token family)
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return RefreshClaims{}, fmt.Errorf("invalid or missing token ID (jti) claim")
+	}
+	sid, ok := claims["sid"].(string)
+	if !ok || sid == "" {
+		return RefreshClaims{}, fmt.Errorf("invalid or missing session ID (sid) claim")
+	}
+
+	return RefreshClaims{
+		Subject:   sub,
+		TokenID:   jti,
+		SessionID: sid,
+		IssuedAt:  issuedAt(claims),
+	}, nil
+}
+
+// issuedAt returns the "iat" claim, or the zero time when it is missing so
+// the token counts as older than any password change.
+func issuedAt(claims jwt.MapClaims) time.Time {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(iat), 0).UTC()
+}
+
+// GenerateFlowToken signs a short-lived token that carries the state of a
+// multi-step flow, such as an OAuth redirect. typ keeps the tokens of different
+// flows from being accepted in place of each other.
+func (j *JWTConfig) GenerateFlowToken(typ string, data map[string]any, ttl time.Duration) (string, error) {
+	token := j.newToken()
+
+	claims := token.Claims.(jwt.MapClaims)
+	for key, value := range data {
+		claims[key] = value
+	}
+	claims["iss"] = j.Issuer
+	claims["aud"] = j.Issuer
+	claims["iat"] = time.Now().UTC().Unix()
+	claims["typ"] = typ
+	claims["exp"] = time.Now().UTC().Add(ttl).Unix()
+
+	signedToken, err := j.signedString(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s token: %w", typ, err)
+	}
+
+	return signedToken, nil
+}
+
+// FlowTokenValidate verifies a token created by GenerateFlowToken for the given
+// flow and returns its claims.
+func (j *JWTConfig) FlowTokenValidate(tokenStr, typ string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, j.keyFunc,
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(j.Issuer),
+		jwt.WithAudience(j.Issuer),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("token is not valid")
+	}
+
+	if t, ok := claims["typ"].(string); !ok || t != typ {
+		return nil, fmt.Errorf("invalid token type")
 	}
 
-	return sub, nil
+	return claims, nil
 }
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is one key of a JWTKeySet. Keys loaded from a public key file can only
+// verify tokens.
+type JWTKey struct {
+	ID      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// JWTKeySet holds the asymmetric keys tokens are signed and verified with. One
+// key signs new tokens; every key in the set is accepted for verification, so
+// a new key can be published before it is used and an old one kept until the
+// tokens it signed have expired.
+type JWTKeySet struct {
+	signing *JWTKey
+	keys    map[string]*JWTKey
+}
+
+// LoadJWTKeys reads PEM encoded RSA or Ed25519 keys from the given files and
+// from every *.pem file in dir. A key's ID (kid) is its file name without the
+// extension. The key signingKID signs new tokens; when empty, the private key
+// whose ID sorts last does, so date-named files such as 2026-10.pem rotate on
+// their own.
+func LoadJWTKeys(files []string, dir, signingKID string) (*JWTKeySet, error) {
+	if dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	set := &JWTKeySet{keys: make(map[string]*JWTKey)}
+	for _, file := range files {
+		key, err := loadJWTKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %s: %w", file, err)
+		}
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	if signingKID == "" {
+		ids := make([]string, 0, len(set.keys))
+		for id, key := range set.keys {
+			if key.private != nil {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil, errors.New("no JWT private key to sign tokens with")
+		}
+		sort.Strings(ids)
+		signingKID = ids[len(ids)-1]
+	}
+
+	signing, ok := set.keys[signingKID]
+	if !ok || signing.private == nil {
+		return nil, fmt.Errorf("no JWT private key with ID %q", signingKID)
+	}
+	set.signing = signing
+
+	return set, nil
+}
+
+func loadJWTKey(file string) (*JWTKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key := &JWTKey{ID: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	if k, ok := key.public.(*rsa.PublicKey); ok && k.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public half of every key in the set, ordered by key ID.
+func (s *JWTKeySet) JWKS() []JWK {
+	if s == nil {
+		return []JWK{}
+	}
+
+	jwks := make([]JWK, 0, len(s.keys))
+	for _, key := range s.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.method.Alg()}
+		switch k := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		}
+		jwks = append(jwks, jwk)
+	}
+	sort.Slice(jwks, func(a, b int) bool { return jwks[a].Kid < jwks[b].Kid })
+
+	return jwks
+}
+
+// verificationKey returns the public key for a token's kid header, checking the
+// token uses that key's algorithm.
+func (s *JWTKeySet) verificationKey(token *jwt.Token) (crypto.PublicKey, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("missing key ID (kid) header")
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
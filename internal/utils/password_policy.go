@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character classes a PasswordPolicy can require.
+const (
+	PasswordClassLower  = "lower"
+	PasswordClassUpper  = "upper"
+	PasswordClassLetter = "letter"
+	PasswordClassDigit  = "digit"
+	PasswordClassSymbol = "symbol"
+)
+
+var passwordClassMessages = map[string]string{
+	PasswordClassLower:  "a lowercase letter",
+	PasswordClassUpper:  "an uppercase letter",
+	PasswordClassLetter: "a letter",
+	PasswordClassDigit:  "a number",
+	PasswordClassSymbol: "a symbol",
+}
+
+// PasswordPolicy is what new passwords must satisfy.
+type PasswordPolicy struct {
+	MinLength int
+	// RequiredClasses are Password* class names, e.g. "letter" and "digit".
+	RequiredClasses []string
+	// BannedWords may not appear in a password, ignoring case.
+	BannedWords []string
+	// BreachedDir holds a breached password corpus in k-anonymity range
+	// files: one file per first 5 hex digits of the SHA-1 of a password, named
+	// after them (e.g. 5BAA6 or 5BAA6.txt), with one "SUFFIX:COUNT" line per
+	// password. Empty skips the check.
+	BreachedDir string
+}
+
+// PasswordRules is overridden from the -password-* flags.
+var PasswordRules = PasswordPolicy{
+	MinLength:       8,
+	RequiredClasses: []string{PasswordClassLetter, PasswordClassDigit},
+	BannedWords:     []string{"varaden"},
+}
+
+// ValidatePasswordClasses checks that every class name is known.
+func ValidatePasswordClasses(classes []string) error {
+	for _, class := range classes {
+		if _, ok := passwordClassMessages[class]; !ok {
+			return fmt.Errorf("unknown password character class %q", class)
+		}
+	}
+	return nil
+}
+
+// PasswordPolicyError lists the rules a password breaks. ErrorHandler reports
+// it like a failed validation of Field.
+type PasswordPolicyError struct {
+	Field      string
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, strings.Join(e.Violations, " "))
+}
+
+// Check returns a *PasswordPolicyError for field when password breaks the
+// policy. userInputs, such as the user's email address, are banned too: an
+// email address by its local part.
+func (p PasswordPolicy) Check(field, password string, userInputs ...string) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("Password must be at least %d characters long.", p.MinLength))
+	}
+
+	for _, class := range p.RequiredClasses {
+		if !strings.ContainsFunc(password, passwordClassFunc(class)) {
+			violations = append(violations, fmt.Sprintf("Password must contain %s.", passwordClassMessages[class]))
+		}
+	}
+
+	lower := strings.ToLower(password)
+	for _, word := range p.bannedWords(userInputs) {
+		if strings.Contains(lower, word) {
+			violations = append(violations, fmt.Sprintf("Password must not contain %q.", word))
+		}
+	}
+
+	breached, err := p.breached(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		violations = append(violations, "Password has appeared in a data breach. Choose a different one.")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Field: field, Violations: violations}
+	}
+	return nil
+}
+
+// bannedWords returns the lowercased banned words, skipping ones too short to
+// ban without rejecting ordinary passwords.
+func (p PasswordPolicy) bannedWords(userInputs []string) []string {
+	words := make([]string, 0, len(p.BannedWords)+len(userInputs))
+	for _, word := range p.BannedWords {
+		words = append(words, strings.ToLower(strings.TrimSpace(word)))
+	}
+	for _, input := range userInputs {
+		local, _, _ := strings.Cut(input, "@")
+		words = append(words, strings.ToLower(strings.TrimSpace(local)))
+	}
+
+	banned := words[:0]
+	for _, word := range words {
+		if utf8.RuneCountInString(word) >= 3 {
+			banned = append(banned, word)
+		}
+	}
+	return banned
+}
+
+// breached looks the password up in the corpus. Only the range file of its
+// hash prefix is read; a missing file means no breached password has it.
+func (p PasswordPolicy) breached(password string) (bool, error) {
+	if p.BreachedDir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	var file *os.File
+	var err error
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		file, err = os.Open(filepath.Join(p.BreachedDir, name))
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func passwordClassFunc(class string) func(rune) bool {
+	switch class {
+	case PasswordClassLower:
+		return unicode.IsLower
+	case PasswordClassUpper:
+		return unicode.IsUpper
+	case PasswordClassLetter:
+		return unicode.IsLetter
+	case PasswordClassDigit:
+		return unicode.IsDigit
+	default:
+		return func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) }
+	}
+}
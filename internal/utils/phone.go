@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+var (
+	// E.164: a country code and subscriber number of at most 15 digits
+	e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	// Bangladeshi mobile numbers: +880 1[3-9] followed by 8 digits
+	bdMobileRegex = regexp.MustCompile(`^\+8801[3-9][0-9]{8}$`)
+)
+
+// NormalizePhone converts a phone number to E.164. Numbers without a country
+// code are taken to be Bangladeshi, so 01712345678, 8801712345678,
+// 00880 1712-345678 and +880 1712 345678 all become +8801712345678.
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+
+	switch {
+	case strings.HasPrefix(phone, "+"):
+	case strings.HasPrefix(phone, "00"):
+		phone = "+" + phone[2:]
+	case strings.HasPrefix(phone, "880"):
+		phone = "+" + phone
+	case strings.HasPrefix(phone, "0"):
+		phone = "+880" + phone[1:]
+	case len(phone) == 10 && strings.HasPrefix(phone, "1"):
+		phone = "+880" + phone
+	default:
+		return "", ErrInvalidPhone
+	}
+
+	// People often keep the trunk 0 after the country code: +880 017...
+	if strings.HasPrefix(phone, "+8800") {
+		phone = "+880" + phone[5:]
+	}
+
+	if !e164Regex.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	if strings.HasPrefix(phone, "+880") && !bdMobileRegex.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+
+	return phone, nil
+}
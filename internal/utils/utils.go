@@ -3,14 +3,19 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/base64"
-	mathrand "math/rand"
+	"math/big"
 	"strconv"
+	"unicode/utf8"
 )
 
 // GenerateRandomNumber generates a random 6-digit(a random number between 100000 and 999999 ) number as a string.
+// It uses crypto/rand, as the numbers are used as one-time codes.
 func GenerateRandomNumber() string {
-	num := mathrand.Intn(900000) + 100000
-	return strconv.Itoa(num)
+	num, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		panic(err)
+	}
+	return strconv.FormatInt(num.Int64()+100000, 10)
 }
 
 func GenerateRandomString(length int) string {
@@ -21,3 +26,11 @@ func GenerateRandomString(length int) string {
 	}
 	return base64.URLEncoding.EncodeToString(b)[:length]
 }
+
+// Truncate shortens s to at most n characters so it fits a VARCHAR(n) column.
+func Truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -1,8 +1,6 @@
 package utils
 
 import (
-	"regexp"
-
 	"github.com/go-playground/validator/v10"
 )
 
@@ -16,15 +14,13 @@ func Validator() *validator.Validate {
 	return validate
 }
 
+// Password checks a field against PasswordRules. It cannot see who the
+// password is for or say which rule failed, so the auth handlers call
+// PasswordRules.Check with the user's email instead.
 func Password(field validator.FieldLevel) bool {
 	value, ok := field.Field().Interface().(string)
 	if ok {
-		hasDigit := regexp.MustCompile(`[0-9]`).MatchString(value)
-		hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(value)
-
-		if !hasDigit || !hasLetter {
-			return false
-		}
+		return PasswordRules.Check(field.FieldName(), value) == nil
 	}
 
 	return true